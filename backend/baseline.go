@@ -0,0 +1,282 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 基线文件与config.yaml放在同一目录
+const baselineFileName = "baseline.yaml"
+
+// 服务相对基线的分类
+const (
+	baselineExpected   = "expected"
+	baselineUnexpected = "unexpected"
+)
+
+// 监听的匹配条件，字段留空（端口为0）表示不限制
+type ListenerMatcher struct {
+	Port     int    `yaml:"port" json:"port,omitempty"`
+	Protocol string `yaml:"protocol" json:"protocol,omitempty"`
+	Address  string `yaml:"address" json:"address,omitempty"`
+	Process  string `yaml:"process" json:"process,omitempty"` // 进程名称
+	Netns    string `yaml:"netns" json:"netns,omitempty"`
+}
+
+// 基线中声明的一个允许的监听，必须指定端口
+type BaselineRule struct {
+	ListenerMatcher `yaml:",inline"`
+	Description     string `yaml:"description" json:"description,omitempty"`
+}
+
+type baselineFile struct {
+	Listeners []BaselineRule `yaml:"listeners"`
+}
+
+// 服务列表与基线的比较结果
+type BaselineStatus struct {
+	Configured bool           `json:"configured"`
+	Time       time.Time      `json:"time"`
+	Expected   []Service      `json:"expected"`
+	Unexpected []Service      `json:"unexpected"`
+	Missing    []BaselineRule `json:"missing"`
+}
+
+func (r ListenerMatcher) String() string {
+	var parts []string
+	if r.Port != 0 {
+		parts = append(parts, fmt.Sprintf("port=%d", r.Port))
+	}
+	if r.Protocol != "" {
+		parts = append(parts, "protocol="+r.Protocol)
+	}
+	if r.Address != "" {
+		parts = append(parts, "address="+r.Address)
+	}
+	if r.Process != "" {
+		parts = append(parts, "process="+r.Process)
+	}
+	if r.Netns != "" {
+		parts = append(parts, "netns="+r.Netns)
+	}
+	return strings.Join(parts, " ")
+}
+
+// 判断服务是否满足匹配条件
+func (r ListenerMatcher) matches(service Service) bool {
+	if r.Port != 0 && service.LocalPort != fmt.Sprint(r.Port) {
+		return false
+	}
+	if r.Protocol != "" && !strings.EqualFold(r.Protocol, service.Protocol) {
+		return false
+	}
+	if r.Address != "" && normalizeAddr(r.Address) != normalizeAddr(service.LocalAddr) {
+		return false
+	}
+	if r.Process != "" && r.Process != service.Name && (service.Process == nil || r.Process != service.Process.Comm) {
+		return false
+	}
+	if r.Netns != "" && r.Netns != service.Netns {
+		return false
+	}
+	return true
+}
+
+func validateListenerMatcher(rule ListenerMatcher) error {
+	if rule.Port < 0 || rule.Port > 65535 {
+		return fmt.Errorf("无效的端口 %d", rule.Port)
+	}
+	switch strings.ToLower(rule.Protocol) {
+	case "", "tcp", "udp", "sctp", "raw":
+	default:
+		return fmt.Errorf("无效的协议 %q", rule.Protocol)
+	}
+	return nil
+}
+
+// Baseline 加载基线文件并在文件修改后自动重新加载
+type Baseline struct {
+	path string
+
+	mu      sync.RWMutex
+	rules   []BaselineRule
+	loaded  bool
+	modTime time.Time
+}
+
+// NewBaseline 创建使用指定基线文件的Baseline，文件不存在时不做比较
+func NewBaseline(path string) *Baseline {
+	b := &Baseline{path: path}
+	b.reload()
+	return b
+}
+
+// 基线文件默认路径
+func baselinePath() string {
+	return filepath.Join(filepath.Dir(getConfigPath()), baselineFileName)
+}
+
+// 基线文件发生变化时重新加载
+func (b *Baseline) reload() {
+	info, err := os.Stat(b.path)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil {
+		if b.loaded {
+			log.Printf("基线文件 %s 已不存在，停止基线比较\n", b.path)
+		}
+		b.rules, b.loaded, b.modTime = nil, false, time.Time{}
+		return
+	}
+	if b.loaded && info.ModTime().Equal(b.modTime) {
+		return
+	}
+
+	content, err := os.ReadFile(b.path)
+	if err != nil {
+		log.Printf("读取基线文件失败: %v\n", err)
+		return
+	}
+	var file baselineFile
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		// 保留之前加载的基线
+		log.Printf("解析基线文件失败: %v\n", err)
+		return
+	}
+
+	rules := make([]BaselineRule, 0, len(file.Listeners))
+	for i, rule := range file.Listeners {
+		if rule.Port == 0 {
+			log.Printf("忽略基线文件中的第 %d 条规则: 缺少port\n", i+1)
+			continue
+		}
+		if err := validateListenerMatcher(rule.ListenerMatcher); err != nil {
+			log.Printf("忽略基线文件中的第 %d 条规则: %v\n", i+1, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	b.rules, b.loaded, b.modTime = rules, true, info.ModTime()
+	log.Printf("加载了 %d 条基线规则\n", len(rules))
+}
+
+// Evaluate 将服务列表与基线比较，并在每个服务上标注分类。未配置基线时不做修改
+func (b *Baseline) Evaluate(services []Service) BaselineStatus {
+	b.reload()
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	status := BaselineStatus{
+		Configured: b.loaded,
+		Time:       time.Now(),
+		Expected:   []Service{},
+		Unexpected: []Service{},
+		Missing:    []BaselineRule{},
+	}
+	if !b.loaded {
+		return status
+	}
+
+	matched := make([]bool, len(b.rules))
+	for i := range services {
+		service := &services[i]
+		service.Baseline = baselineUnexpected
+		for j, rule := range b.rules {
+			if rule.matches(*service) {
+				service.Baseline = baselineExpected
+				matched[j] = true
+			}
+		}
+		if service.Baseline == baselineExpected {
+			status.Expected = append(status.Expected, *service)
+		} else {
+			status.Unexpected = append(status.Unexpected, *service)
+		}
+	}
+
+	for i, rule := range b.rules {
+		if !matched[i] {
+			status.Missing = append(status.Missing, rule)
+		}
+	}
+	return status
+}
+
+// Watch 持续比较采样器的每次采样结果，记录新出现的异常
+func (b *Baseline) Watch(sampler *Sampler) {
+	ch, _ := sampler.Subscribe()
+	go func() {
+		var previous *BaselineStatus
+		for snapshot := range ch {
+			status := b.Evaluate(append([]Service(nil), snapshot.Services...))
+			if status.Configured {
+				logBaselineDrift(previous, &status)
+			}
+			previous = &status
+		}
+	}()
+}
+
+// 只记录相对上一次比较新出现的异常，避免每次采样重复输出
+func logBaselineDrift(previous, current *BaselineStatus) {
+	seen := make(map[string]bool)
+	missing := make(map[string]bool)
+	if previous != nil {
+		for _, service := range previous.Unexpected {
+			seen[serviceID(service)] = true
+		}
+		for _, rule := range previous.Missing {
+			missing[rule.String()] = true
+		}
+	}
+
+	for _, service := range current.Unexpected {
+		if !seen[serviceID(service)] {
+			log.Printf("基线外的监听: %s (%s)\n", serviceID(service), service.Name)
+		}
+	}
+	for _, rule := range current.Missing {
+		if !missing[rule.String()] {
+			log.Printf("基线中的监听缺失: %s\n", rule)
+		}
+	}
+}
+
+// Status 比较当前的服务列表与基线
+func (b *Baseline) Status(sampler *Sampler) (BaselineStatus, error) {
+	snapshot, err := currentSnapshot(sampler)
+	if err != nil {
+		return BaselineStatus{}, err
+	}
+	return b.Evaluate(append([]Service(nil), snapshot.Services...)), nil
+}
+
+// 基线比较结果
+func baselineHandler(baseline *Baseline, sampler *Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到获取基线比较结果的请求")
+		status, err := baseline.Status(sampler)
+		if err != nil {
+			log.Printf("获取基线比较结果失败: %v\n", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+		log.Printf("基线比较: 预期 %d, 基线外 %d, 缺失 %d\n", len(status.Expected), len(status.Unexpected), len(status.Missing))
+	}
+}
@@ -0,0 +1,123 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeBaselineFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 文件系统的时间精度可能不足以区分两次写入
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func baselineService(protocol, addr, port, name string) Service {
+	return Service{Protocol: protocol, LocalAddr: addr, LocalPort: port, Name: name}
+}
+
+func TestBaselineEvaluate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), baselineFileName)
+	writeBaselineFile(t, path, `listeners:
+  - port: 22
+    description: SSH
+  - port: 53
+    protocol: UDP
+  - port: 5432
+    address: 127.0.0.1
+  - port: 8080
+    process: java
+  - port: 443
+    description: 已下线的网关
+`, time.Now())
+
+	baseline := NewBaseline(path)
+	services := []Service{
+		baselineService("tcp", "0.0.0.0", "22", "sshd"),          // 不限地址和协议
+		baselineService("tcp", "::", "22", "sshd"),               // 不限地址和协议
+		baselineService("udp", "127.0.0.53", "53", "resolved"),   // 协议不区分大小写
+		baselineService("tcp", "127.0.0.53", "53", "resolved"),   // 协议不符
+		baselineService("tcp", "::ffff:127.0.0.1", "5432", "pg"), // IPv4映射地址
+		baselineService("tcp", "0.0.0.0", "5432", "pg"),          // 地址不符
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "8080", Name: "tomcat", Process: &ProcessInfo{Comm: "java"}},
+		baselineService("tcp", "0.0.0.0", "8080", "python3"), // 进程不符
+		baselineService("tcp", "0.0.0.0", "6379", "redis"),
+	}
+	want := []string{
+		baselineExpected, baselineExpected, baselineExpected, baselineUnexpected, baselineExpected,
+		baselineUnexpected, baselineExpected, baselineUnexpected, baselineUnexpected,
+	}
+
+	status := baseline.Evaluate(services)
+	if !status.Configured {
+		t.Fatal("基线文件存在时应该已配置")
+	}
+	for i, service := range services {
+		if service.Baseline != want[i] {
+			t.Errorf("%s %s:%s (%s) baseline = %q, want %q", service.Protocol, service.LocalAddr, service.LocalPort, service.Name, service.Baseline, want[i])
+		}
+	}
+	if len(status.Expected) != 5 || len(status.Unexpected) != 4 {
+		t.Errorf("expected=%d unexpected=%d, want 5/4", len(status.Expected), len(status.Unexpected))
+	}
+	if len(status.Missing) != 1 || status.Missing[0].Port != 443 || status.Missing[0].Description != "已下线的网关" {
+		t.Errorf("missing = %+v, want 只有443", status.Missing)
+	}
+}
+
+func TestBaselineNotConfigured(t *testing.T) {
+	baseline := NewBaseline(filepath.Join(t.TempDir(), baselineFileName))
+	services := []Service{baselineService("tcp", "0.0.0.0", "22", "sshd")}
+	status := baseline.Evaluate(services)
+	if status.Configured || services[0].Baseline != "" {
+		t.Errorf("没有基线文件时不应比较: configured=%v baseline=%q", status.Configured, services[0].Baseline)
+	}
+}
+
+func TestBaselineReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), baselineFileName)
+	start := time.Now().Add(-time.Hour)
+	writeBaselineFile(t, path, `listeners:
+  - port: 22
+  - description: 缺少端口
+  - port: 70000
+  - port: 80
+    protocol: icmp
+  - port: 443
+    protocol: tcp
+`, start)
+
+	baseline := NewBaseline(path)
+	if len(baseline.rules) != 2 || baseline.rules[0].Port != 22 || baseline.rules[1].Port != 443 {
+		t.Fatalf("rules = %+v, want 跳过无效的规则后只剩22和443", baseline.rules)
+	}
+
+	// 文件修改后重新加载
+	writeBaselineFile(t, path, "listeners:\n  - port: 8080\n", start.Add(time.Minute))
+	baseline.reload()
+	if len(baseline.rules) != 1 || baseline.rules[0].Port != 8080 {
+		t.Fatalf("修改后 rules = %+v, want 8080", baseline.rules)
+	}
+
+	// 解析失败时保留之前的基线
+	writeBaselineFile(t, path, "listeners: [", start.Add(2*time.Minute))
+	baseline.reload()
+	if !baseline.loaded || len(baseline.rules) != 1 || baseline.rules[0].Port != 8080 {
+		t.Fatalf("解析失败后 loaded=%v rules=%+v, want 保留8080", baseline.loaded, baseline.rules)
+	}
+
+	// 文件删除后停止比较
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	baseline.reload()
+	if baseline.loaded || baseline.rules != nil {
+		t.Errorf("文件删除后 loaded=%v rules=%+v", baseline.loaded, baseline.rules)
+	}
+}
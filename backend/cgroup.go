@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// systemd单元类型后缀
+var systemdUnitSuffixes = []string{".service", ".scope", ".socket", ".mount", ".swap", ".timer", ".path", ".target"}
+
+// 读取进程所在的cgroup路径：优先cgroup v2统一层级，其次v1的systemd层级
+func readProcCgroup(pid int) string {
+	file, err := os.Open(filepath.Join(procRoot, strconv.Itoa(pid), "cgroup"))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var unified, systemd, first string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// 每行格式为 hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			unified = parts[2]
+		case parts[1] == "name=systemd":
+			systemd = parts[2]
+		case first == "":
+			first = parts[2]
+		}
+	}
+
+	switch {
+	case unified != "" && unified != "/":
+		return unified
+	case systemd != "":
+		return systemd
+	case unified != "":
+		return unified
+	}
+	return first
+}
+
+// 从cgroup路径中取出最内层的systemd单元及其所属slice
+func systemdUnitFromCgroup(cgroup string) (unit, slice string) {
+	for _, elem := range strings.Split(cgroup, "/") {
+		if elem == "" {
+			continue
+		}
+		if strings.HasSuffix(elem, ".slice") {
+			slice = elem
+			continue
+		}
+		for _, suffix := range systemdUnitSuffixes {
+			if strings.HasSuffix(elem, suffix) {
+				unit = elem
+				break
+			}
+		}
+	}
+	return unit, slice
+}
+
+// 进程的cgroup路径及其所属的systemd单元
+type cgroupInfo struct {
+	path  string
+	unit  string
+	slice string
+}
+
+// 读取进程的cgroup信息，结果在解析器内按PID缓存
+func (r *processResolver) Cgroup(pid int) cgroupInfo {
+	if info, ok := r.cgroups[pid]; ok {
+		return info
+	}
+
+	info := cgroupInfo{path: readProcCgroup(pid)}
+	if info.path != "" {
+		info.unit, info.slice = systemdUnitFromCgroup(info.path)
+	}
+	r.cgroups[pid] = info
+	return info
+}
+
+// 根据服务的主进程补充systemd单元和cgroup信息
+func applyCgroupInfo(service *Service, resolver *processResolver) {
+	if service.Process == nil {
+		return
+	}
+
+	info := resolver.Cgroup(service.Process.PID)
+	if info.path == "" {
+		return
+	}
+
+	service.Cgroup = info.path
+	service.Unit, service.Slice = info.unit, info.slice
+}
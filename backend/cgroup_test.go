@@ -0,0 +1,67 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSystemdUnitFromCgroup(t *testing.T) {
+	tests := []struct {
+		cgroup string
+		unit   string
+		slice  string
+	}{
+		{"/system.slice/nginx.service", "nginx.service", "system.slice"},
+		{"/user.slice/user-1000.slice/user@1000.service/app.slice/app-foo.scope", "app-foo.scope", "app.slice"},
+		{"/system.slice/docker.socket", "docker.socket", "system.slice"},
+		{"/docker/0123456789abcdef", "", ""},
+		{"/", "", ""},
+	}
+	for _, tt := range tests {
+		unit, slice := systemdUnitFromCgroup(tt.cgroup)
+		if unit != tt.unit || slice != tt.slice {
+			t.Errorf("systemdUnitFromCgroup(%q) = %q, %q, want %q, %q", tt.cgroup, unit, slice, tt.unit, tt.slice)
+		}
+	}
+}
+
+func TestProcessResolverCgroupCache(t *testing.T) {
+	root := t.TempDir()
+	orig := procRoot
+	procRoot = root
+	t.Cleanup(func() { procRoot = orig })
+
+	dir := filepath.Join(root, "42")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := "12:memory:/system.slice/nginx.service\n0::/system.slice/nginx.service\n"
+	if err := os.WriteFile(filepath.Join(dir, "cgroup"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := newProcessResolver()
+	service := Service{Process: &ProcessInfo{PID: 42}}
+	applyCgroupInfo(&service, resolver)
+	if service.Cgroup != "/system.slice/nginx.service" || service.Unit != "nginx.service" || service.Slice != "system.slice" {
+		t.Fatalf("cgroup=%s unit=%s slice=%s", service.Cgroup, service.Unit, service.Slice)
+	}
+
+	// 同一采集周期内不再重复读取 /proc/<pid>/cgroup
+	if err := os.Remove(filepath.Join(dir, "cgroup")); err != nil {
+		t.Fatal(err)
+	}
+	again := Service{Process: &ProcessInfo{PID: 42}}
+	applyCgroupInfo(&again, resolver)
+	if again.Unit != "nginx.service" {
+		t.Errorf("缓存的cgroup信息丢失: %+v", again)
+	}
+
+	// 新的采集周期重新读取
+	fresh := Service{Process: &ProcessInfo{PID: 42}}
+	applyCgroupInfo(&fresh, newProcessResolver())
+	if fresh.Cgroup != "" || fresh.Unit != "" {
+		t.Errorf("进程已退出时不应有cgroup信息: %+v", fresh)
+	}
+}
@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"log"
+	"path/filepath"
+)
+
+// 套接字采集后端，通过配置文件的 collector 字段选择
+const (
+	collectorAuto    = "auto"    // 优先netlink，失败时读取/proc/net
+	collectorNetlink = "netlink" // NETLINK_SOCK_DIAG
+	collectorProcfs  = "procfs"  // /proc/net/tcp 等文本表
+	collectorSS      = "ss"      // 调用ss命令（旧方式）
+)
+
+// 按配置的后端采集主机命名空间中的套接字
+func collectSockets(filter socketFilter) ([]socketEntry, error) {
+	return collectSocketsIn(filepath.Join(procRoot, "net"), filter)
+}
+
+// 按配置的后端采集当前线程所在命名空间中的套接字，netDir为该命名空间的 /proc/.../net
+func collectSocketsIn(netDir string, filter socketFilter) ([]socketEntry, error) {
+	switch config.Collector {
+	case collectorNetlink:
+		return collectNetlinkWithExtra(netDir, filter)
+	case collectorProcfs:
+		return collectProcNet(netDir, filter)
+	default:
+		entries, err := collectNetlinkWithExtra(netDir, filter)
+		if err == nil {
+			return entries, nil
+		}
+		log.Printf("netlink采集失败，改为读取/proc/net: %v\n", err)
+		return collectProcNet(netDir, filter)
+	}
+}
+
+// netlink只负责TCP/UDP，SCTP和原始套接字仍从/proc/net读取
+func collectNetlinkWithExtra(netDir string, filter socketFilter) ([]socketEntry, error) {
+	entries, err := collectNetlink(filter)
+	if err != nil {
+		return nil, err
+	}
+	return append(entries, collectProcNetExtra(netDir, filter)...), nil
+}
@@ -0,0 +1,152 @@
+package backend
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 连接模式下采集的TCP状态：除LISTEN和CLOSE以外的全部状态
+var connectionFilter = socketFilter{
+	TCPStates: 1<<tcpEstablished | 1<<tcpSynSent | 1<<tcpSynRecv | 1<<tcpFinWait1 | 1<<tcpFinWait2 |
+		1<<tcpTimeWait | 1<<tcpCloseWait | 1<<tcpLastAck | 1<<tcpClosing,
+}
+
+// 每个监听端口展示的来源IP数量
+const topRemoteCount = 5
+
+// 监听端口的客户端连接统计
+type ConnectionStats struct {
+	Clients    int            `json:"clients"`     // 已建立的连接数
+	TopRemotes []RemoteCount  `json:"top_remotes"` // 连接数最多的来源IP
+	States     map[string]int `json:"states"`      // 按状态统计，键为getServiceState的名称
+}
+
+type RemoteCount struct {
+	IP    string `json:"ip"`
+	Count int    `json:"count"`
+}
+
+// 获取当前的TCP连接（ForeignAddr为对端地址）
+func getConnections() ([]Service, error) {
+	entries, err := collectNamespaceSockets(connectionFilter)
+	if err != nil {
+		return nil, err
+	}
+	return buildServices(entries), nil
+}
+
+// 格式化 地址:端口，IPv6地址加方括号
+func formatHostPort(ip net.IP, ipv6 bool, port int) string {
+	return net.JoinHostPort(formatSocketIP(ip, ipv6), strconv.Itoa(port))
+}
+
+// 去掉IPv4映射前缀，便于比较本地地址
+func normalizeAddr(addr string) string {
+	return strings.TrimPrefix(addr, "::ffff:")
+}
+
+func isWildcardAddr(addr string) bool {
+	return addr == "0.0.0.0" || addr == "::" || addr == "*"
+}
+
+// 连接与监听的匹配程度，0表示不匹配。本地地址所在的套接字表（IPv4或IPv6）
+// 决定了连接属于哪个地址族的监听：双栈主机上 ::ffff: 地址的连接属于 :: 的监听，
+// 普通IPv4连接属于 0.0.0.0 的监听
+func listenerMatchScore(listener, conn Service) int {
+	sameFamily := listener.LocalAddr != "*" && conn.LocalAddr != "*" &&
+		strings.Contains(listener.LocalAddr, ":") == strings.Contains(conn.LocalAddr, ":")
+	wildcard := isWildcardAddr(listener.LocalAddr)
+	exact := !wildcard && normalizeAddr(conn.LocalAddr) == normalizeAddr(listener.LocalAddr)
+
+	switch {
+	case exact && sameFamily:
+		return 4
+	case wildcard && sameFamily:
+		return 3
+	case exact:
+		return 2
+	case wildcard:
+		return 1
+	}
+	return 0
+}
+
+// 将连接归属到对应的监听端口并统计客户端数、来源IP和状态分布。
+// 每个连接只归属到匹配程度最高的一个监听，避免双栈主机上重复统计
+func attachConnectionStats(services []Service, conns []Service) {
+	// 按命名空间和端口索引TCP监听
+	listeners := make(map[string][]int)
+	for i := range services {
+		if services[i].Protocol != "tcp" {
+			continue
+		}
+		key := services[i].Netns + "|" + services[i].LocalPort
+		listeners[key] = append(listeners[key], i)
+	}
+
+	stats := make(map[int]*ConnectionStats)
+	remotes := make(map[int]map[string]int)
+	for _, indexes := range listeners {
+		for _, index := range indexes {
+			stats[index] = &ConnectionStats{States: make(map[string]int)}
+			remotes[index] = make(map[string]int)
+		}
+	}
+
+	for _, conn := range conns {
+		best, bestScore := -1, 0
+		for _, index := range listeners[conn.Netns+"|"+conn.LocalPort] {
+			if score := listenerMatchScore(services[index], conn); score > bestScore {
+				best, bestScore = index, score
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		stats[best].States[conn.State]++
+		if conn.State == getServiceState("ESTAB") {
+			stats[best].Clients++
+			if host, _, err := net.SplitHostPort(conn.ForeignAddr); err == nil {
+				remotes[best][normalizeAddr(host)]++
+			}
+		}
+	}
+
+	for index, stat := range stats {
+		for ip, count := range remotes[index] {
+			stat.TopRemotes = append(stat.TopRemotes, RemoteCount{IP: ip, Count: count})
+		}
+		sort.Slice(stat.TopRemotes, func(a, b int) bool {
+			if stat.TopRemotes[a].Count != stat.TopRemotes[b].Count {
+				return stat.TopRemotes[a].Count > stat.TopRemotes[b].Count
+			}
+			return stat.TopRemotes[a].IP < stat.TopRemotes[b].IP
+		})
+		if len(stat.TopRemotes) > topRemoteCount {
+			stat.TopRemotes = stat.TopRemotes[:topRemoteCount]
+		}
+
+		services[index].Connections = stat
+	}
+}
+
+// 返回当前全部TCP连接
+func connectionsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("接收到获取连接列表的请求")
+	conns, err := getConnections()
+	if err != nil {
+		log.Printf("获取连接信息失败: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(conns)
+	log.Printf("成功返回 %d 个连接\n", len(conns))
+}
@@ -0,0 +1,88 @@
+package backend
+
+import "testing"
+
+func conn(local, port, remote string) Service {
+	return Service{Protocol: "tcp", LocalAddr: local, LocalPort: port, ForeignAddr: remote, State: getServiceState("ESTAB")}
+}
+
+func TestAttachConnectionStatsDualStack(t *testing.T) {
+	services := []Service{
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "80"},
+		{Protocol: "tcp", LocalAddr: "::", LocalPort: "80"},
+		{Protocol: "udp", LocalAddr: "0.0.0.0", LocalPort: "80"},
+	}
+	conns := []Service{
+		conn("10.0.0.1", "80", "10.0.0.2:50000"),
+		conn("10.0.0.1", "80", "10.0.0.2:50001"),
+		// IPv6监听接受的IPv4连接
+		conn("::ffff:10.0.0.1", "80", "[::ffff:10.0.0.3]:50002"),
+		conn("2001:db8::1", "80", "[2001:db8::2]:50003"),
+		// 其他端口的连接
+		conn("10.0.0.1", "443", "10.0.0.2:50004"),
+	}
+	attachConnectionStats(services, conns)
+
+	v4, v6 := services[0].Connections, services[1].Connections
+	if v4 == nil || v6 == nil {
+		t.Fatal("TCP监听应该有连接统计")
+	}
+	if v4.Clients != 2 || v6.Clients != 2 {
+		t.Errorf("clients = %d/%d, want 2/2（每个连接只统计一次）", v4.Clients, v6.Clients)
+	}
+	if len(v4.TopRemotes) != 1 || v4.TopRemotes[0] != (RemoteCount{IP: "10.0.0.2", Count: 2}) {
+		t.Errorf("IPv4来源 = %+v", v4.TopRemotes)
+	}
+	if len(v6.TopRemotes) != 2 || v6.TopRemotes[0].IP != "10.0.0.3" {
+		t.Errorf("IPv6来源 = %+v", v6.TopRemotes)
+	}
+	if services[2].Connections != nil {
+		t.Error("UDP套接字不应该有连接统计")
+	}
+}
+
+func TestAttachConnectionStatsSpecificAddress(t *testing.T) {
+	services := []Service{
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "8080"},
+		{Protocol: "tcp", LocalAddr: "127.0.0.1", LocalPort: "8080"},
+		{Protocol: "tcp", LocalAddr: "127.0.0.1", LocalPort: "8080", Netns: "app"},
+	}
+	conns := []Service{
+		conn("127.0.0.1", "8080", "127.0.0.1:40000"),
+		conn("192.168.1.10", "8080", "192.168.1.20:40001"),
+		{Protocol: "tcp", LocalAddr: "192.168.1.10", LocalPort: "8080", ForeignAddr: "192.168.1.20:40002", State: getServiceState("TIME-WAIT")},
+	}
+	attachConnectionStats(services, conns)
+
+	if got := services[1].Connections.Clients; got != 1 {
+		t.Errorf("指定地址的监听 clients = %d, want 1", got)
+	}
+	wildcard := services[0].Connections
+	if wildcard.Clients != 1 || wildcard.States[getServiceState("TIME-WAIT")] != 1 {
+		t.Errorf("通配地址的监听 = %+v", wildcard)
+	}
+	if got := services[2].Connections.Clients; got != 0 {
+		t.Errorf("其他命名空间的监听 clients = %d, want 0", got)
+	}
+}
+
+func TestListenerMatchScore(t *testing.T) {
+	tests := []struct {
+		listener, conn string
+		want           int
+	}{
+		{"127.0.0.1", "127.0.0.1", 4},
+		{"0.0.0.0", "10.0.0.1", 3},
+		{"::", "::ffff:10.0.0.1", 3},
+		{"::", "10.0.0.1", 1},
+		{"0.0.0.0", "::ffff:10.0.0.1", 1},
+		{"*", "10.0.0.1", 1},
+		{"127.0.0.1", "10.0.0.1", 0},
+	}
+	for _, tt := range tests {
+		got := listenerMatchScore(Service{LocalAddr: tt.listener}, Service{LocalAddr: tt.conn})
+		if got != tt.want {
+			t.Errorf("listenerMatchScore(%q, %q) = %d, want %d", tt.listener, tt.conn, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 容器运行时在cgroup路径中留下的容器ID特征
+var containerCgroupPatterns = []struct {
+	Runtime string
+	Regex   *regexp.Regexp
+}{
+	{"docker", regexp.MustCompile(`docker[-/]([0-9a-f]{64})`)},
+	{"containerd", regexp.MustCompile(`cri-containerd[-:]([0-9a-f]{64})`)},
+	{"cri-o", regexp.MustCompile(`crio[-:]([0-9a-f]{64})`)},
+	{"podman", regexp.MustCompile(`libpod[-:]([0-9a-f]{64})`)},
+	{"kubernetes", regexp.MustCompile(`kubepods.*/([0-9a-f]{64})`)},
+}
+
+// 从cgroup路径中识别容器ID和运行时
+func containerIDFromCgroup(cgroup string) (id, runtime string) {
+	for _, pattern := range containerCgroupPatterns {
+		if matches := pattern.Regex.FindStringSubmatch(cgroup); len(matches) > 1 {
+			return matches[1], pattern.Runtime
+		}
+	}
+	return "", ""
+}
+
+// Docker Engine API 返回的容器信息（只取需要的字段）
+type dockerContainer struct {
+	ID    string       `json:"Id"`
+	Names []string     `json:"Names"`
+	Image string       `json:"Image"`
+	Ports []dockerPort `json:"Ports"`
+}
+
+type dockerPort struct {
+	IP          string `json:"IP"`
+	PrivatePort int    `json:"PrivatePort"`
+	PublicPort  int    `json:"PublicPort"`
+	Type        string `json:"Type"`
+}
+
+func (c dockerContainer) name() string {
+	if len(c.Names) == 0 {
+		if len(c.ID) > 12 {
+			return c.ID[:12]
+		}
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// Docker Engine API 默认套接字
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// 配置为该值时不查询Docker
+const dockerSocketDisabled = "none"
+
+// 容器列表缓存时间，避免每次刷新服务都请求Docker；
+// 查询失败的结果缓存更久，Docker不可用时不会让每次采集都等待超时
+const (
+	dockerCacheTTL        = 10 * time.Second
+	dockerFailureCacheTTL = time.Minute
+	dockerRequestTimeout  = 3 * time.Second
+)
+
+// 最近一次查询Docker的结果
+type dockerCacheEntry struct {
+	socketPath string
+	containers []dockerContainer
+	err        error
+	time       time.Time
+}
+
+var (
+	dockerCache   dockerCacheEntry
+	dockerCacheMu sync.Mutex
+)
+
+// 查询运行中的容器，结果会缓存一段时间。查询期间不持有锁
+func listDockerContainers(socketPath string) ([]dockerContainer, error) {
+	dockerCacheMu.Lock()
+	cached := dockerCache
+	dockerCacheMu.Unlock()
+
+	ttl := dockerCacheTTL
+	if cached.err != nil {
+		ttl = dockerFailureCacheTTL
+	}
+	if cached.socketPath == socketPath && !cached.time.IsZero() && time.Since(cached.time) < ttl {
+		return cached.containers, cached.err
+	}
+
+	containers, err := fetchDockerContainers(socketPath)
+	if err != nil && (cached.err == nil || cached.socketPath != socketPath) {
+		// 只在开始失败时记录，之后的重试不重复输出
+		log.Printf("查询Docker容器失败，%s 内不再重试: %v\n", dockerFailureCacheTTL, err)
+	}
+
+	dockerCacheMu.Lock()
+	dockerCache = dockerCacheEntry{socketPath: socketPath, containers: containers, err: err, time: time.Now()}
+	dockerCacheMu.Unlock()
+	return containers, err
+}
+
+// 通过unix套接字查询Docker Engine API获取运行中的容器
+func fetchDockerContainers(socketPath string) ([]dockerContainer, error) {
+	client := &http.Client{
+		Timeout: dockerRequestTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+
+	// 主机名在unix套接字上没有意义，仅用于构造URL
+	resp, err := client.Get("http://docker/containers/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Docker API返回状态码 %d", resp.StatusCode)
+	}
+
+	var containers []dockerContainer
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, err
+	}
+	return containers, nil
+}
+
+// 为服务标注所属容器：优先根据进程cgroup识别，其次匹配Docker发布的端口（docker-proxy）
+func annotateContainers(services []Service) {
+	var containers []dockerContainer
+	if config.DockerSocket != "" {
+		if _, err := os.Stat(config.DockerSocket); err == nil {
+			// 失败时listDockerContainers已经记录日志
+			containers, _ = listDockerContainers(config.DockerSocket)
+		}
+	}
+
+	byID := make(map[string]*dockerContainer, len(containers))
+	published := make(map[string]*dockerContainer)
+	for i := range containers {
+		c := &containers[i]
+		byID[c.ID] = c
+		for _, port := range c.Ports {
+			if port.PublicPort != 0 {
+				published[port.Type+":"+strconv.Itoa(port.PublicPort)] = c
+			}
+		}
+	}
+
+	// 连接模式下同一进程会出现很多次，按cgroup路径只解析一次
+	type cgroupContainer struct{ id, runtime string }
+	parsed := make(map[string]cgroupContainer)
+
+	for i := range services {
+		service := &services[i]
+
+		container, ok := parsed[service.Cgroup]
+		if !ok {
+			container.id, container.runtime = containerIDFromCgroup(service.Cgroup)
+			parsed[service.Cgroup] = container
+		}
+		if id, runtime := container.id, container.runtime; id != "" {
+			service.ContainerID = id
+			service.ContainerRuntime = runtime
+			if c := byID[id]; c != nil {
+				service.ContainerName = c.name()
+				service.ContainerImage = c.Image
+			}
+			continue
+		}
+
+		if c := published[service.Protocol+":"+service.LocalPort]; c != nil {
+			service.ContainerID = c.ID
+			service.ContainerRuntime = "docker"
+			service.ContainerName = c.name()
+			service.ContainerImage = c.Image
+		}
+	}
+}
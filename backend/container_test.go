@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// 在unix套接字上模拟Docker Engine API
+func fakeDockerSocket(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "docker.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("无法创建unix套接字: %v", err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return path
+}
+
+func resetDockerCache(t *testing.T) {
+	t.Helper()
+	dockerCacheMu.Lock()
+	dockerCache = dockerCacheEntry{}
+	dockerCacheMu.Unlock()
+	t.Cleanup(func() {
+		dockerCacheMu.Lock()
+		dockerCache = dockerCacheEntry{}
+		dockerCacheMu.Unlock()
+	})
+}
+
+const fakeContainersJSON = `[
+	{"Id": "4c01db0b339c4c01db0b339c4c01db0b339c4c01db0b339c4c01db0b339cabcd", "Names": ["/web"], "Image": "nginx:1.25",
+	 "Ports": [{"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"}]},
+	{"Id": "9f8e7d6c5b4a9f8e7d6c5b4a9f8e7d6c5b4a9f8e7d6c5b4a9f8e7d6c5b4a1234", "Names": ["/db"], "Image": "postgres:16"}
+]`
+
+func TestListDockerContainers(t *testing.T) {
+	resetDockerCache(t)
+	var requests int32
+	socket := fakeDockerSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/containers/json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(fakeContainersJSON))
+	})
+
+	containers, err := listDockerContainers(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(containers) != 2 || containers[0].name() != "web" || containers[1].Image != "postgres:16" {
+		t.Fatalf("containers = %+v", containers)
+	}
+
+	// 缓存期内不再请求
+	if _, err := listDockerContainers(socket); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("请求了 %d 次, want 1", n)
+	}
+}
+
+func TestListDockerContainersCachesFailure(t *testing.T) {
+	resetDockerCache(t)
+	var requests int32
+	socket := fakeDockerSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "daemon unavailable", http.StatusInternalServerError)
+	})
+
+	if _, err := listDockerContainers(socket); err == nil {
+		t.Fatal("Docker返回错误时应该返回错误")
+	}
+	if _, err := listDockerContainers(socket); err == nil {
+		t.Fatal("缓存的失败结果应该返回错误")
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("失败后请求了 %d 次, want 1", n)
+	}
+
+	// 换了套接字路径时不使用旧的结果
+	if _, err := listDockerContainers(filepath.Join(t.TempDir(), "missing.sock")); err == nil {
+		t.Error("套接字不存在时应该返回错误")
+	}
+}
+
+func TestAnnotateContainers(t *testing.T) {
+	resetDockerCache(t)
+	socket := fakeDockerSocket(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(fakeContainersJSON))
+	})
+	orig := config.DockerSocket
+	config.DockerSocket = socket
+	t.Cleanup(func() { config.DockerSocket = orig })
+
+	services := []Service{
+		// docker-proxy发布的端口
+		{Protocol: "tcp", LocalPort: "8080"},
+		// 进程cgroup在容器内
+		{Protocol: "tcp", LocalPort: "5432", Cgroup: "/system.slice/docker-9f8e7d6c5b4a9f8e7d6c5b4a9f8e7d6c5b4a9f8e7d6c5b4a9f8e7d6c5b4a1234.scope"},
+		{Protocol: "tcp", LocalPort: "22"},
+	}
+	annotateContainers(services)
+
+	if services[0].ContainerName != "web" || services[0].ContainerRuntime != "docker" || services[0].ContainerImage != "nginx:1.25" {
+		t.Errorf("发布端口: %+v", services[0])
+	}
+	if services[1].ContainerName != "db" || services[1].ContainerImage != "postgres:16" {
+		t.Errorf("cgroup: %+v", services[1])
+	}
+	if services[2].ContainerID != "" {
+		t.Errorf("主机进程不应属于容器: %+v", services[2])
+	}
+}
+
+func TestContainerIDFromCgroup(t *testing.T) {
+	id := "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		cgroup  string
+		runtime string
+	}{
+		{"/system.slice/docker-" + id + ".scope", "docker"},
+		{"/docker/" + id, "docker"},
+		{"/system.slice/cri-containerd-" + id + ".scope", "containerd"},
+		{"/machine.slice/libpod-" + id + ".scope", "podman"},
+		{"/kubepods/besteffort/pod1234/" + id, "kubernetes"},
+	}
+	for _, tt := range tests {
+		gotID, runtime := containerIDFromCgroup(tt.cgroup)
+		if gotID != id || runtime != tt.runtime {
+			t.Errorf("containerIDFromCgroup(%q) = %q, %q, want %q", tt.cgroup, gotID, runtime, tt.runtime)
+		}
+	}
+	if gotID, _ := containerIDFromCgroup("/user.slice/user-1000.slice/session-1.scope"); gotID != "" {
+		t.Errorf("非容器cgroup返回了 %q", gotID)
+	}
+}
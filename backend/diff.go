@@ -0,0 +1,250 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// 服务变化的原因
+const (
+	changeAddress = "address" // 同一端口绑定到了不同的地址
+	changeProcess = "process" // 端口被另一个程序占用
+	changePID     = "pid"     // 同一程序重启，进程号变化
+	changeState   = "state"
+)
+
+// 同一端口在两个快照之间的变化
+type ServiceChange struct {
+	Previous Service  `json:"previous"`
+	Current  Service  `json:"current"`
+	Reasons  []string `json:"reasons"`
+}
+
+// 两个快照之间的差异
+type SnapshotDiff struct {
+	From    SnapshotInfo    `json:"from"`
+	To      SnapshotInfo    `json:"to"`
+	Added   []Service       `json:"added"`
+	Removed []Service       `json:"removed"`
+	Changed []ServiceChange `json:"changed"`
+}
+
+// 同一协议、端口和网络命名空间的监听视为同一个服务，地址不同时记为变化
+func servicePortKey(service Service) string {
+	return service.Netns + "|" + service.Protocol + "|" + service.LocalPort
+}
+
+// 进程的程序标识：优先使用可执行文件路径，其次进程名
+func serviceProgram(service Service) string {
+	if service.Process != nil {
+		if service.Process.Exe != "" {
+			return service.Process.Exe
+		}
+		if service.Process.Comm != "" {
+			return service.Process.Comm
+		}
+	}
+	return service.Name
+}
+
+func servicePID(service Service) int {
+	if service.Process != nil {
+		return service.Process.PID
+	}
+	return 0
+}
+
+// 比较同一端口前后两次的监听，返回变化原因
+func serviceChangeReasons(previous, current Service) []string {
+	var reasons []string
+	if normalizeAddr(previous.LocalAddr) != normalizeAddr(current.LocalAddr) {
+		reasons = append(reasons, changeAddress)
+	}
+	if serviceProgram(previous) != serviceProgram(current) {
+		reasons = append(reasons, changeProcess)
+	} else if servicePID(previous) != servicePID(current) {
+		reasons = append(reasons, changePID)
+	}
+	if previous.State != current.State {
+		reasons = append(reasons, changeState)
+	}
+	return reasons
+}
+
+// 计算两组服务之间的差异：先按完全相同的地址配对，剩余的按端口配对，仍未配对的为新增或移除
+func diffServices(from, to []Service) (added, removed []Service, changed []ServiceChange) {
+	added, removed, changed = []Service{}, []Service{}, []ServiceChange{}
+
+	previous := make(map[string][]Service)
+	for _, service := range from {
+		key := servicePortKey(service)
+		previous[key] = append(previous[key], service)
+	}
+
+	current := make(map[string][]Service)
+	for _, service := range to {
+		key := servicePortKey(service)
+		current[key] = append(current[key], service)
+	}
+
+	keys := make(map[string]bool)
+	for key := range previous {
+		keys[key] = true
+	}
+	for key := range current {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		before := previous[key]
+		after := current[key]
+		unmatched := make([]Service, 0, len(after))
+
+		// 地址相同的直接配对
+		for _, service := range after {
+			matched := -1
+			for i, old := range before {
+				if normalizeAddr(old.LocalAddr) == normalizeAddr(service.LocalAddr) {
+					matched = i
+					break
+				}
+			}
+			if matched < 0 {
+				unmatched = append(unmatched, service)
+				continue
+			}
+			if reasons := serviceChangeReasons(before[matched], service); len(reasons) > 0 {
+				changed = append(changed, ServiceChange{Previous: before[matched], Current: service, Reasons: reasons})
+			}
+			before = append(before[:matched:matched], before[matched+1:]...)
+		}
+
+		// 同一端口换了地址
+		for _, service := range unmatched {
+			if len(before) == 0 {
+				added = append(added, service)
+				continue
+			}
+			changed = append(changed, ServiceChange{Previous: before[0], Current: service, Reasons: serviceChangeReasons(before[0], service)})
+			before = before[1:]
+		}
+
+		removed = append(removed, before...)
+	}
+
+	sortServices(added)
+	sortServices(removed)
+	sort.Slice(changed, func(i, j int) bool {
+		return serviceLess(changed[i].Current, changed[j].Current)
+	})
+	return added, removed, changed
+}
+
+func serviceLess(a, b Service) bool {
+	if a.Protocol != b.Protocol {
+		return a.Protocol < b.Protocol
+	}
+	pa, _ := strconv.Atoi(a.LocalPort)
+	pb, _ := strconv.Atoi(b.LocalPort)
+	if pa != pb {
+		return pa < pb
+	}
+	if a.LocalAddr != b.LocalAddr {
+		return a.LocalAddr < b.LocalAddr
+	}
+	return a.Netns < b.Netns
+}
+
+func sortServices(services []Service) {
+	sort.Slice(services, func(i, j int) bool {
+		return serviceLess(services[i], services[j])
+	})
+}
+
+func snapshotInfo(snapshot *Snapshot) SnapshotInfo {
+	return SnapshotInfo{ID: snapshot.ID, Time: snapshot.Time, Services: len(snapshot.Services)}
+}
+
+// 计算两个快照之间的差异
+func diffSnapshots(from, to *Snapshot) SnapshotDiff {
+	diff := SnapshotDiff{From: snapshotInfo(from), To: snapshotInfo(to)}
+	diff.Added, diff.Removed, diff.Changed = diffServices(from.Services, to.Services)
+	return diff
+}
+
+// 根据参数定位快照：<name>_id 为快照ID，<name> 为时间；都未指定时返回 current
+func resolveSnapshot(history *History, r *http.Request, name string, current func() (*Snapshot, error)) (*Snapshot, error) {
+	query := r.URL.Query()
+	if value := query.Get(name + "_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的快照ID %q", value)
+		}
+		return history.Get(id)
+	}
+	if value := query.Get(name); value != "" {
+		t, err := parseHistoryTime(value)
+		if err != nil {
+			return nil, err
+		}
+		return history.At(t)
+	}
+	if current != nil {
+		return current()
+	}
+	return nil, fmt.Errorf("缺少%s或%s_id参数", name, name)
+}
+
+// 当前的服务列表：优先使用采样器的最新结果
+func currentSnapshot(sampler *Sampler) (*Snapshot, error) {
+	if snapshot := sampler.Latest(); snapshot != nil {
+		return snapshot, nil
+	}
+	services, err := getServices()
+	if err != nil {
+		return nil, err
+	}
+	return &Snapshot{Time: time.Now(), Services: services}, nil
+}
+
+// 两个时间点之间的服务变化。from/to 为时间（RFC3339或Unix时间戳），
+// from_id/to_id 为快照ID；未指定to时与当前的服务列表比较
+func historyDiffHandler(history *History, sampler *Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到比较历史快照的请求")
+
+		from, err := resolveSnapshot(history, r, "from", nil)
+		if err != nil {
+			writeSnapshotError(w, err)
+			return
+		}
+		to, err := resolveSnapshot(history, r, "to", func() (*Snapshot, error) {
+			return currentSnapshot(sampler)
+		})
+		if err != nil {
+			writeSnapshotError(w, err)
+			return
+		}
+
+		diff := diffSnapshots(from, to)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(diff)
+		log.Printf("快照 #%d 与 #%d 之间: 新增 %d, 移除 %d, 变化 %d\n",
+			diff.From.ID, diff.To.ID, len(diff.Added), len(diff.Removed), len(diff.Changed))
+	}
+}
+
+func writeSnapshotError(w http.ResponseWriter, err error) {
+	log.Printf("定位历史快照失败: %v\n", err)
+	status := http.StatusBadRequest
+	if errors.Is(err, errSnapshotNotFound) {
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// 构造diff测试用的服务：地址、端口、程序路径和进程号
+func diffService(addr, port, exe string, pid int) Service {
+	return Service{
+		Protocol:  "tcp",
+		LocalAddr: addr,
+		LocalPort: port,
+		State:     "Listening",
+		Process:   &ProcessInfo{PID: pid, Exe: exe},
+	}
+}
+
+func diffServiceText(service Service) string {
+	text := service.LocalAddr + ":" + service.LocalPort
+	if service.Netns != "" {
+		text = service.Netns + "|" + text
+	}
+	return text
+}
+
+func diffServiceTexts(services []Service) []string {
+	texts := []string{}
+	for _, service := range services {
+		texts = append(texts, diffServiceText(service))
+	}
+	return texts
+}
+
+func diffChangeTexts(changes []ServiceChange) []string {
+	texts := []string{}
+	for _, change := range changes {
+		texts = append(texts, diffServiceText(change.Previous)+" -> "+diffServiceText(change.Current)+" "+strings.Join(change.Reasons, ","))
+	}
+	return texts
+}
+
+func TestDiffServices(t *testing.T) {
+	nginx := "/usr/sbin/nginx"
+	tests := []struct {
+		name    string
+		from    []Service
+		to      []Service
+		added   []string
+		removed []string
+		changed []string
+	}{
+		{
+			name: "没有变化",
+			from: []Service{diffService("0.0.0.0", "80", nginx, 10)},
+			to:   []Service{diffService("0.0.0.0", "80", nginx, 10)},
+		},
+		{
+			name:  "新增和移除",
+			from:  []Service{diffService("0.0.0.0", "80", nginx, 10), diffService("0.0.0.0", "22", "/usr/sbin/sshd", 1)},
+			to:    []Service{diffService("0.0.0.0", "80", nginx, 10), diffService("0.0.0.0", "443", nginx, 10)},
+			added: []string{"0.0.0.0:443"}, removed: []string{"0.0.0.0:22"},
+		},
+		{
+			name:    "地址变化",
+			from:    []Service{diffService("0.0.0.0", "80", nginx, 10)},
+			to:      []Service{diffService("127.0.0.1", "80", nginx, 10)},
+			changed: []string{"0.0.0.0:80 -> 127.0.0.1:80 address"},
+		},
+		{
+			name: "IPv4映射地址视为相同",
+			from: []Service{diffService("::ffff:10.0.0.1", "80", nginx, 10)},
+			to:   []Service{diffService("10.0.0.1", "80", nginx, 10)},
+		},
+		{
+			name:    "端口被另一个程序占用",
+			from:    []Service{diffService("0.0.0.0", "8080", nginx, 10)},
+			to:      []Service{diffService("0.0.0.0", "8080", "/usr/bin/python3", 30)},
+			changed: []string{"0.0.0.0:8080 -> 0.0.0.0:8080 process"},
+		},
+		{
+			name:    "同一程序重启",
+			from:    []Service{diffService("0.0.0.0", "80", nginx, 10)},
+			to:      []Service{diffService("0.0.0.0", "80", nginx, 11)},
+			changed: []string{"0.0.0.0:80 -> 0.0.0.0:80 pid"},
+		},
+		{
+			name:    "地址和进程同时变化",
+			from:    []Service{diffService("0.0.0.0", "80", nginx, 10)},
+			to:      []Service{diffService("::", "80", "/usr/sbin/apache2", 40)},
+			changed: []string{"0.0.0.0:80 -> :::80 address,process"},
+		},
+		{
+			name: "同一端口的多个监听，移除中间的一个",
+			from: []Service{
+				diffService("127.0.0.1", "53", "/usr/sbin/dnsmasq", 5),
+				diffService("10.0.0.1", "53", "/usr/sbin/dnsmasq", 5),
+				diffService("::1", "53", "/usr/sbin/dnsmasq", 5),
+			},
+			to: []Service{
+				diffService("127.0.0.1", "53", "/usr/sbin/dnsmasq", 5),
+				diffService("::1", "53", "/usr/sbin/dnsmasq", 5),
+			},
+			removed: []string{"10.0.0.1:53"},
+		},
+		{
+			// 先按地址配对中间的监听，剩下的监听按顺序与新地址配对
+			name: "同一端口的多个监听，配对后剩余的换了地址",
+			from: []Service{
+				diffService("127.0.0.1", "53", "/usr/sbin/dnsmasq", 5),
+				diffService("10.0.0.1", "53", "/usr/sbin/dnsmasq", 5),
+				diffService("::1", "53", "/usr/sbin/dnsmasq", 5),
+			},
+			to: []Service{
+				diffService("10.0.0.1", "53", "/usr/sbin/dnsmasq", 6),
+				diffService("::1", "53", "/usr/sbin/dnsmasq", 5),
+				diffService("192.168.1.5", "53", "/usr/sbin/dnsmasq", 5),
+			},
+			changed: []string{
+				"10.0.0.1:53 -> 10.0.0.1:53 pid",
+				"127.0.0.1:53 -> 192.168.1.5:53 address",
+			},
+		},
+		{
+			name: "同一端口的监听增多",
+			from: []Service{diffService("127.0.0.1", "53", "/usr/sbin/dnsmasq", 5)},
+			to: []Service{
+				diffService("10.0.0.1", "53", "/usr/sbin/dnsmasq", 5),
+				diffService("127.0.0.1", "53", "/usr/sbin/dnsmasq", 5),
+				diffService("::1", "53", "/usr/sbin/dnsmasq", 5),
+			},
+			added: []string{"10.0.0.1:53", "::1:53"},
+		},
+		{
+			name: "不同网络命名空间中的相同端口",
+			from: []Service{diffService("0.0.0.0", "80", nginx, 10)},
+			to: []Service{
+				diffService("0.0.0.0", "80", nginx, 10),
+				func() Service { s := diffService("0.0.0.0", "80", nginx, 20); s.Netns = "app"; return s }(),
+			},
+			added: []string{"app|0.0.0.0:80"},
+		},
+	}
+
+	for _, tt := range tests {
+		from := append([]Service(nil), tt.from...)
+		added, removed, changed := diffServices(tt.from, tt.to)
+
+		if got, want := diffServiceTexts(added), append([]string{}, tt.added...); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: added = %q, want %q", tt.name, got, want)
+		}
+		if got, want := diffServiceTexts(removed), append([]string{}, tt.removed...); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: removed = %q, want %q", tt.name, got, want)
+		}
+		if got, want := diffChangeTexts(changed), append([]string{}, tt.changed...); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: changed = %q, want %q", tt.name, got, want)
+		}
+		// 配对时从中间删除监听不能改写调用者的切片
+		for i := range from {
+			if diffServiceText(tt.from[i]) != diffServiceText(from[i]) || servicePID(tt.from[i]) != servicePID(from[i]) {
+				t.Errorf("%s: from[%d] 被修改为 %s pid=%d", tt.name, i, diffServiceText(tt.from[i]), servicePID(tt.from[i]))
+			}
+		}
+	}
+}
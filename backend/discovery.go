@@ -0,0 +1,359 @@
+package backend
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"html"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDiscoveryInterval = 300 // 秒
+	defaultDiscoveryTimeout  = 5 * time.Second
+	maxDiscoveryRedirects    = 10
+	maxDiscoveryBody         = 64 * 1024 // 查找标题时读取的最大字节数
+	maxDiscoveryTitle        = 200
+)
+
+var htmlTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// 配置文件中的HTTP端点发现配置
+type DiscoveryConfig struct {
+	Interval int    `yaml:"interval"` // 秒，0为默认值300，小于0表示不发现
+	Timeout  string `yaml:"timeout"`
+}
+
+// 重定向中的一跳
+type HTTPRedirect struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+}
+
+// 通过某个网卡地址访问服务的结果
+type HTTPEndpoint struct {
+	URL          string         `json:"url"`
+	Interface    string         `json:"interface"`
+	IP           string         `json:"ip"`
+	StatusCode   int            `json:"status_code,omitempty"`
+	Title        string         `json:"title,omitempty"`
+	Redirects    []HTTPRedirect `json:"redirects,omitempty"`
+	FinalURL     string         `json:"final_url,omitempty"` // 跟随重定向后的地址
+	Location     string         `json:"location,omitempty"`  // 没有跟随的重定向：指向其他主机或次数过多
+	ContentType  string         `json:"content_type,omitempty"`
+	Server       string         `json:"server,omitempty"`
+	ResponseTime float64        `json:"response_time_ms"`
+	Error        string         `json:"error,omitempty"`
+	Time         time.Time      `json:"time"`
+}
+
+// 一个服务的发现结果
+type serviceEndpoints struct {
+	path      string
+	endpoints []HTTPEndpoint
+}
+
+// HTTPDiscovery 定期访问HTTP服务，记录状态码、页面标题、重定向和响应时间
+type HTTPDiscovery struct {
+	interval      time.Duration
+	timeout       time.Duration
+	store         *Store
+	sampler       *Sampler
+	fingerprinter *Fingerprinter
+
+	mu      sync.RWMutex
+	results map[string]serviceEndpoints // 服务ID -> 发现结果
+}
+
+// NewHTTPDiscovery 根据配置创建HTTP端点发现
+func NewHTTPDiscovery(cfg DiscoveryConfig, store *Store, sampler *Sampler, fingerprinter *Fingerprinter) *HTTPDiscovery {
+	d := &HTTPDiscovery{
+		interval:      time.Duration(cfg.Interval) * time.Second,
+		timeout:       defaultDiscoveryTimeout,
+		store:         store,
+		sampler:       sampler,
+		fingerprinter: fingerprinter,
+		results:       make(map[string]serviceEndpoints),
+	}
+	if cfg.Interval == 0 {
+		d.interval = defaultDiscoveryInterval * time.Second
+	}
+	if cfg.Timeout != "" {
+		if timeout, err := time.ParseDuration(cfg.Timeout); err == nil && timeout > 0 {
+			d.timeout = timeout
+		} else {
+			log.Printf("HTTP端点发现的timeout无效，使用默认值 %s: %s\n", defaultDiscoveryTimeout, cfg.Timeout)
+		}
+	}
+	return d
+}
+
+// Start 在后台开始定期发现，第一轮在协议识别完成第一轮后开始
+func (d *HTTPDiscovery) Start() {
+	if d.interval <= 0 {
+		log.Println("HTTP端点发现已禁用")
+		return
+	}
+
+	go func() {
+		// 等待协议识别完成第一轮，以便知道哪些服务是HTTP
+		<-d.fingerprinter.Ready()
+		d.discoverAll()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			d.discoverAll()
+		}
+	}()
+}
+
+// 识别为HTTP或保存了URL路径的TCP服务
+func (d *HTTPDiscovery) isHTTP(service Service) (useTLS bool, ok bool) {
+	if service.Protocol != "tcp" || (service.Netns != "" && service.Netns != "host") {
+		return false, false
+	}
+	if fp, found := d.fingerprinter.Lookup(serviceID(service)); found && fp.Protocol == "http" {
+		return fp.TLS, true
+	}
+	if _, found := d.store.URLPath(serviceID(service)); found {
+		return service.LocalPort == "443" || service.LocalPort == "8443", true
+	}
+	return false, false
+}
+
+func (d *HTTPDiscovery) discoverAll() {
+	snapshot, err := currentSnapshot(d.sampler)
+	if err != nil {
+		log.Printf("获取服务列表失败，跳过本次HTTP端点发现: %v\n", err)
+		return
+	}
+	interfaces, err := getLocalInterfaces()
+	if err != nil {
+		log.Printf("获取网卡地址失败: %v\n", err)
+	}
+
+	var services []Service
+	for _, service := range snapshot.Services {
+		if _, ok := d.isHTTP(service); ok {
+			services = append(services, service)
+		}
+	}
+
+	results := make([]serviceEndpoints, len(services))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for i, service := range services {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, service Service) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = d.discover(service, interfaces)
+		}(i, service)
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.results = make(map[string]serviceEndpoints, len(services))
+	for i, service := range services {
+		d.results[serviceID(service)] = results[i]
+	}
+}
+
+// Refresh 立即重新发现指定的服务，例如修改了URL路径之后
+func (d *HTTPDiscovery) Refresh(id string) ([]HTTPEndpoint, bool) {
+	snapshot, err := currentSnapshot(d.sampler)
+	if err != nil {
+		return nil, false
+	}
+	for _, service := range snapshot.Services {
+		if serviceID(service) != id || (service.Netns != "" && service.Netns != "host") {
+			continue
+		}
+		if _, ok := d.isHTTP(service); !ok {
+			return nil, false
+		}
+		interfaces, _ := getLocalInterfaces()
+		result := d.discover(service, interfaces)
+
+		d.mu.Lock()
+		d.results[id] = result
+		d.mu.Unlock()
+		return result.endpoints, true
+	}
+	return nil, false
+}
+
+// 访问地址：通配地址使用每个网卡的IP，与访问链接一致；指定地址只访问该地址
+func discoveryTargets(service Service, interfaces []InterfaceInfo) []InterfaceInfo {
+	addr := normalizeAddr(service.LocalAddr)
+	if !isWildcardAddr(addr) {
+		return []InterfaceInfo{{Name: addr, IP: addr}}
+	}
+
+	return interfaces
+}
+
+func (d *HTTPDiscovery) discover(service Service, interfaces []InterfaceInfo) serviceEndpoints {
+	useTLS, _ := d.isHTTP(service)
+	path, ok := d.store.URLPath(serviceID(service))
+	if !ok {
+		path = "/"
+	}
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+
+	result := serviceEndpoints{path: path}
+	for _, target := range discoveryTargets(service, interfaces) {
+		url := scheme + "://" + net.JoinHostPort(target.IP, service.LocalPort) + path
+		endpoint := d.fetch(url)
+		endpoint.Interface = target.Name
+		endpoint.IP = target.IP
+		result.endpoints = append(result.endpoints, endpoint)
+	}
+	return result
+}
+
+// 访问URL并跟随重定向，记录每一跳的状态码。重定向到其他主机时停在该跳，
+// 返回的是重定向响应本身
+func (d *HTTPDiscovery) fetch(url string) HTTPEndpoint {
+	endpoint := HTTPEndpoint{URL: url, Time: time.Now()}
+
+	client := &http.Client{
+		Timeout: d.timeout,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// 只跟随同一主机内的重定向，例如不访问外部的登录页面
+			if !strings.EqualFold(req.URL.Hostname(), via[0].URL.Hostname()) || len(via) > maxDiscoveryRedirects {
+				endpoint.Location = req.URL.String()
+				return http.ErrUseLastResponse
+			}
+			endpoint.Redirects = append(endpoint.Redirects, HTTPRedirect{
+				URL:        req.Response.Request.URL.String(),
+				StatusCode: req.Response.StatusCode,
+			})
+			return nil
+		},
+	}
+
+	start := time.Now()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		endpoint.Error = err.Error()
+		return endpoint
+	}
+	req.Header.Set("User-Agent", "port-monitor")
+	resp, err := client.Do(req)
+	if err != nil {
+		endpoint.ResponseTime = float64(time.Since(start).Microseconds()) / 1000
+		endpoint.Error = err.Error()
+		return endpoint
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBody))
+	endpoint.ResponseTime = float64(time.Since(start).Microseconds()) / 1000
+	endpoint.StatusCode = resp.StatusCode
+	endpoint.ContentType = resp.Header.Get("Content-Type")
+	endpoint.Server = resp.Header.Get("Server")
+	if final := resp.Request.URL.String(); final != url {
+		endpoint.FinalURL = final
+	}
+	if strings.Contains(endpoint.ContentType, "html") || endpoint.ContentType == "" {
+		endpoint.Title = htmlTitle(body)
+	}
+	return endpoint
+}
+
+func htmlTitle(body []byte) string {
+	match := htmlTitlePattern.FindSubmatch(body)
+	if match == nil {
+		return ""
+	}
+	title := strings.Join(strings.Fields(html.UnescapeString(string(match[1]))), " ")
+	if runes := []rune(title); len(runes) > maxDiscoveryTitle {
+		title = string(runes[:maxDiscoveryTitle]) + "..."
+	}
+	return title
+}
+
+// Annotate 把发现结果标注到服务上，URL路径修改后的旧结果不再显示
+func (d *HTTPDiscovery) Annotate(services []Service) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	for i := range services {
+		if services[i].Netns != "" && services[i].Netns != "host" {
+			continue
+		}
+		id := serviceID(services[i])
+		result, ok := d.results[id]
+		if !ok {
+			continue
+		}
+		path, found := d.store.URLPath(id)
+		if !found {
+			path = "/"
+		}
+		if path == result.path {
+			services[i].Endpoints = append([]HTTPEndpoint(nil), result.endpoints...)
+		}
+	}
+}
+
+// HTTP端点：GET返回全部发现结果（?service_id= 只返回指定服务），
+// POST ?service_id= 立即重新发现该服务
+func endpointsHandler(discovery *HTTPDiscovery) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("service_id")
+
+		if r.Method == http.MethodPost {
+			if id == "" {
+				http.Error(w, "缺少service_id参数", http.StatusBadRequest)
+				return
+			}
+			endpoints, ok := discovery.Refresh(id)
+			if !ok {
+				http.Error(w, "服务不存在或不是HTTP服务", http.StatusNotFound)
+				return
+			}
+			log.Printf("重新发现服务 %s 的HTTP端点\n", id)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(endpoints)
+			return
+		}
+
+		type result struct {
+			ServiceID string         `json:"service_id"`
+			Path      string         `json:"path"`
+			Endpoints []HTTPEndpoint `json:"endpoints"`
+		}
+		discovery.mu.RLock()
+		results := []result{}
+		for serviceID, endpoints := range discovery.results {
+			if id == "" || serviceID == id {
+				results = append(results, result{serviceID, endpoints.path, endpoints.endpoints})
+			}
+		}
+		discovery.mu.RUnlock()
+		sort.Slice(results, func(i, j int) bool {
+			return results[i].ServiceID < results[j].ServiceID
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results)
+	}
+}
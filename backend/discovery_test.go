@@ -0,0 +1,184 @@
+package backend
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func testDiscovery(t *testing.T) *HTTPDiscovery {
+	t.Helper()
+	store := NewStore(filepath.Join(t.TempDir(), dataFileName))
+	return NewHTTPDiscovery(DiscoveryConfig{Timeout: "2s"}, store, nil, nil)
+}
+
+func TestDiscoveryFetchRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/login", http.StatusFound)
+		case "/login":
+			http.Redirect(w, r, "/login/", http.StatusMovedPermanently)
+		default:
+			w.Header().Set("Server", "nginx")
+			fmt.Fprint(w, "<html><head><title>登录 - 控制台</title></head></html>")
+		}
+	}))
+	defer server.Close()
+
+	endpoint := testDiscovery(t).fetch(server.URL + "/")
+	if endpoint.Error != "" {
+		t.Fatal(endpoint.Error)
+	}
+	if len(endpoint.Redirects) != 2 ||
+		endpoint.Redirects[0] != (HTTPRedirect{URL: server.URL + "/", StatusCode: http.StatusFound}) ||
+		endpoint.Redirects[1] != (HTTPRedirect{URL: server.URL + "/login", StatusCode: http.StatusMovedPermanently}) {
+		t.Errorf("redirects = %+v", endpoint.Redirects)
+	}
+	if endpoint.StatusCode != http.StatusOK || endpoint.FinalURL != server.URL+"/login/" || endpoint.Location != "" {
+		t.Errorf("status_code=%d final_url=%s location=%s", endpoint.StatusCode, endpoint.FinalURL, endpoint.Location)
+	}
+	if endpoint.Title != "登录 - 控制台" || endpoint.Server != "nginx" {
+		t.Errorf("title=%q server=%q", endpoint.Title, endpoint.Server)
+	}
+}
+
+func TestDiscoveryFetchMaxRedirects(t *testing.T) {
+	// 每次都重定向到下一个地址
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		http.Redirect(w, r, "/"+strconv.Itoa(n+1), http.StatusFound)
+	}))
+	defer server.Close()
+
+	endpoint := testDiscovery(t).fetch(server.URL + "/0")
+	if endpoint.Error != "" {
+		t.Fatal(endpoint.Error)
+	}
+	if len(endpoint.Redirects) != maxDiscoveryRedirects {
+		t.Errorf("跟随了 %d 次重定向, want %d", len(endpoint.Redirects), maxDiscoveryRedirects)
+	}
+	last := strconv.Itoa(maxDiscoveryRedirects)
+	if endpoint.StatusCode != http.StatusFound || endpoint.FinalURL != server.URL+"/"+last {
+		t.Errorf("status_code=%d final_url=%s, want 302 %s/%s", endpoint.StatusCode, endpoint.FinalURL, server.URL, last)
+	}
+	if want := server.URL + "/" + strconv.Itoa(maxDiscoveryRedirects+1); endpoint.Location != want {
+		t.Errorf("location = %s, want %s", endpoint.Location, want)
+	}
+}
+
+func TestDiscoveryFetchStopsAtOtherHost(t *testing.T) {
+	var visited atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		visited.Add(1)
+	}))
+	defer other.Close()
+	// 同一个服务，主机名不同
+	external := strings.Replace(other.URL, "127.0.0.1", "localhost", 1) + "/sso"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/admin", http.StatusFound)
+		default:
+			http.Redirect(w, r, external, http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	endpoint := testDiscovery(t).fetch(server.URL + "/")
+	if endpoint.Error != "" {
+		t.Fatal(endpoint.Error)
+	}
+	if len(endpoint.Redirects) != 1 || endpoint.Redirects[0].URL != server.URL+"/" {
+		t.Errorf("redirects = %+v, want 只跟随同一主机内的重定向", endpoint.Redirects)
+	}
+	if endpoint.StatusCode != http.StatusFound || endpoint.FinalURL != server.URL+"/admin" || endpoint.Location != external {
+		t.Errorf("status_code=%d final_url=%s location=%s", endpoint.StatusCode, endpoint.FinalURL, endpoint.Location)
+	}
+	if visited.Load() != 0 {
+		t.Error("不应访问其他主机")
+	}
+}
+
+func TestDiscoveryFetchTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<title>Grafana</title>")
+	}))
+	defer server.Close()
+
+	// 自签名证书也可以访问
+	endpoint := testDiscovery(t).fetch(server.URL + "/")
+	if endpoint.Error != "" || endpoint.StatusCode != http.StatusOK || endpoint.Title != "Grafana" {
+		t.Errorf("endpoint = %+v", endpoint)
+	}
+}
+
+func TestDiscoveryFetchTitleOnlyForHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"html": "<title>x</title>"}`)
+	}))
+	defer server.Close()
+
+	endpoint := testDiscovery(t).fetch(server.URL + "/")
+	if endpoint.Title != "" || endpoint.ContentType != "application/json" {
+		t.Errorf("title=%q content_type=%q", endpoint.Title, endpoint.ContentType)
+	}
+}
+
+func TestHTMLTitle(t *testing.T) {
+	long := strings.Repeat("标", maxDiscoveryTitle+5)
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"<html><head><title>Jenkins</title></head></html>", "Jenkins"},
+		{`<TITLE lang="en">Tom &amp; Jerry &#20013;&#25991; &lt;dev&gt;</TITLE>`, "Tom & Jerry 中文 <dev>"},
+		{"<title>\n  Dashboard\n\t- Home\n</title>", "Dashboard - Home"},
+		{"<title></title>", ""},
+		{"<html><body>没有标题</body></html>", ""},
+		{"<title>" + long + "</title>", strings.Repeat("标", maxDiscoveryTitle) + "..."},
+	}
+	for _, tt := range tests {
+		if got := htmlTitle([]byte(tt.body)); got != tt.want {
+			t.Errorf("htmlTitle(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDiscoveryAnnotate(t *testing.T) {
+	d := testDiscovery(t)
+	service := Service{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "8080"}
+	id := serviceID(service)
+	d.results[id] = serviceEndpoints{path: "/", endpoints: []HTTPEndpoint{{URL: "http://10.0.0.1:8080/", StatusCode: http.StatusOK}}}
+
+	services := []Service{service, {Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "8080", Netns: "app"}}
+	d.Annotate(services)
+	if len(services[0].Endpoints) != 1 || services[0].Endpoints[0].StatusCode != http.StatusOK {
+		t.Errorf("endpoints = %+v", services[0].Endpoints)
+	}
+	if services[1].Endpoints != nil {
+		t.Errorf("其他网络命名空间中的服务不应标注: %+v", services[1].Endpoints)
+	}
+
+	// 修改URL路径后旧的结果不再显示
+	d.store.SetURLPath(id, "/admin")
+	services = []Service{service}
+	d.Annotate(services)
+	if services[0].Endpoints != nil {
+		t.Errorf("URL路径修改后 endpoints = %+v, want 空", services[0].Endpoints)
+	}
+
+	d.results[id] = serviceEndpoints{path: "/admin", endpoints: []HTTPEndpoint{{URL: "http://10.0.0.1:8080/admin", StatusCode: http.StatusUnauthorized}}}
+	d.Annotate(services)
+	if len(services[0].Endpoints) != 1 || services[0].Endpoints[0].StatusCode != http.StatusUnauthorized {
+		t.Errorf("重新发现后 endpoints = %+v", services[0].Endpoints)
+	}
+}
@@ -0,0 +1,498 @@
+package backend
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	eventListenerAdded   = "listener_added"
+	eventListenerRemoved = "listener_removed"
+	eventListenerChanged = "listener_changed"
+	eventRuleFiring      = "rule_firing"   // 规则开始触发
+	eventRuleResolved    = "rule_resolved" // 规则恢复
+)
+
+// 事件通知的处理结果
+const (
+	eventSent      = "sent"
+	eventSilenced  = "silenced"
+	eventDebounced = "debounced"
+)
+
+// 内存中保留的最近事件数量
+const maxRecentEvents = 500
+
+// 监听变化等需要关注的事件
+type Event struct {
+	ID       int64     `json:"id"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Message  string    `json:"message"`
+	Rule     string    `json:"rule,omitempty"` // 发送通知时为触发的规则
+	Service  *Service  `json:"service,omitempty"`
+	Previous *Service  `json:"previous,omitempty"` // listener_changed 的变化前状态
+	Reasons  []string  `json:"reasons,omitempty"`
+	Targets  []string  `json:"targets,omitempty"` // 规则事件中满足条件的对象
+
+	Matches []EventMatch `json:"matches,omitempty"` // 匹配的告警规则及处理结果
+}
+
+type EventMatch struct {
+	Rule   string `json:"rule"`
+	Status string `json:"status"` // sent, silenced, debounced
+}
+
+// 配置文件中的告警配置
+type AlertConfig struct {
+	Notifiers    []NotifierConfig `yaml:"notifiers"`
+	Rules        []AlertRule      `yaml:"rules"`
+	SilenceToken string           `yaml:"silence_token"` // 设置后静默接口需要携带该令牌，否则只接受本机请求
+}
+
+// 告警规则：匹配事件类型和监听，发送到指定的通知方式
+type AlertRule struct {
+	Name            string   `yaml:"name"`
+	Events          []string `yaml:"events"` // 为空表示全部事件类型
+	ListenerMatcher `yaml:",inline"`
+	Notifiers       []string `yaml:"notifiers"` // 为空表示全部通知方式
+	Debounce        string   `yaml:"debounce"`  // 同一服务同类事件的最短通知间隔，例如 10m
+}
+
+type alertRule struct {
+	AlertRule
+	debounce time.Duration
+}
+
+func (r *alertRule) matches(event Event) bool {
+	if len(r.Events) > 0 {
+		found := false
+		for _, eventType := range r.Events {
+			if eventType == event.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if event.Service == nil {
+		return r.ListenerMatcher == ListenerMatcher{}
+	}
+	return r.ListenerMatcher.matches(*event.Service)
+}
+
+// EventEngine 比较相邻两次采样生成事件，按告警规则发送通知
+type EventEngine struct {
+	mu        sync.Mutex
+	notifiers map[string]Notifier
+	order     []string // 通知方式的配置顺序
+	rules     []*alertRule
+	lastSent  map[string]time.Time // 规则|事件类型|服务 -> 最近一次通知时间
+	silences  map[string]time.Time // 规则名称 -> 静默截止时间，空名称表示全部规则
+	events    []Event
+	nextID    int64
+	previous  *Snapshot
+
+	silenceToken string
+}
+
+// NewEventEngine 根据配置创建事件引擎，配置有误的通知方式和规则会被忽略
+func NewEventEngine(cfg AlertConfig) *EventEngine {
+	e := &EventEngine{
+		notifiers: make(map[string]Notifier),
+		lastSent:  make(map[string]time.Time),
+		silences:  make(map[string]time.Time),
+
+		silenceToken: cfg.SilenceToken,
+	}
+
+	for _, notifierConfig := range cfg.Notifiers {
+		notifier, err := newNotifier(notifierConfig)
+		if err != nil {
+			log.Printf("忽略通知方式: %v\n", err)
+			continue
+		}
+		if _, ok := e.notifiers[notifier.Name()]; ok {
+			log.Printf("忽略重复的通知方式: %s\n", notifier.Name())
+			continue
+		}
+		e.notifiers[notifier.Name()] = notifier
+		e.order = append(e.order, notifier.Name())
+	}
+
+	for i, rule := range cfg.Rules {
+		if rule.Name == "" {
+			rule.Name = "rule-" + strconv.Itoa(i+1)
+		}
+		if err := e.validateRule(rule); err != nil {
+			log.Printf("忽略告警规则 %s: %v\n", rule.Name, err)
+			continue
+		}
+		compiled := &alertRule{AlertRule: rule}
+		if rule.Debounce != "" {
+			compiled.debounce, _ = time.ParseDuration(rule.Debounce)
+		}
+		e.rules = append(e.rules, compiled)
+	}
+
+	if len(e.rules) > 0 {
+		log.Printf("加载了 %d 个通知方式和 %d 条告警规则\n", len(e.notifiers), len(e.rules))
+	}
+	return e
+}
+
+func (e *EventEngine) validateRule(rule AlertRule) error {
+	for _, eventType := range rule.Events {
+		switch eventType {
+		case eventListenerAdded, eventListenerRemoved, eventListenerChanged:
+		default:
+			return fmt.Errorf("未知的事件类型 %q", eventType)
+		}
+	}
+	if err := validateListenerMatcher(rule.ListenerMatcher); err != nil {
+		return err
+	}
+	for _, name := range rule.Notifiers {
+		if _, ok := e.notifiers[name]; !ok {
+			return fmt.Errorf("未定义的通知方式 %q", name)
+		}
+	}
+	if rule.Debounce != "" {
+		if _, err := time.ParseDuration(rule.Debounce); err != nil {
+			return fmt.Errorf("debounce无效: %v", err)
+		}
+	}
+	return nil
+}
+
+// Watch 订阅采样器，比较相邻两次采样的服务列表
+func (e *EventEngine) Watch(sampler *Sampler) {
+	ch, _ := sampler.Subscribe()
+	go func() {
+		for snapshot := range ch {
+			e.process(snapshot)
+		}
+	}()
+}
+
+func (e *EventEngine) process(snapshot *Snapshot) {
+	e.mu.Lock()
+	previous := e.previous
+	e.previous = snapshot
+	e.mu.Unlock()
+
+	// 启动后的第一次采样只作为比较的起点
+	if previous == nil {
+		return
+	}
+
+	added, removed, changed := diffServices(previous.Services, snapshot.Services)
+	for i := range added {
+		service := added[i]
+		e.Emit(Event{
+			Type:    eventListenerAdded,
+			Time:    snapshot.Time,
+			Message: "新的监听: " + describeListener(service),
+			Service: &service,
+		})
+	}
+	for i := range removed {
+		service := removed[i]
+		e.Emit(Event{
+			Type:    eventListenerRemoved,
+			Time:    snapshot.Time,
+			Message: "监听消失: " + describeListener(service),
+			Service: &service,
+		})
+	}
+	for i := range changed {
+		change := changed[i]
+		e.Emit(Event{
+			Type:     eventListenerChanged,
+			Time:     snapshot.Time,
+			Message:  fmt.Sprintf("监听变化: %s（原为 %s）", describeListener(change.Current), describeListener(change.Previous)),
+			Service:  &change.Current,
+			Previous: &change.Previous,
+			Reasons:  change.Reasons,
+		})
+	}
+}
+
+// 例如 tcp 0.0.0.0:22 (sshd)
+func describeListener(service Service) string {
+	text := service.Protocol + " " + formatListenAddr(service)
+	if service.Name != "" && service.Name != "N/A" {
+		text += " (" + service.Name + ")"
+	}
+	if service.Netns != "" && service.Netns != "host" {
+		text += " [netns " + service.Netns + "]"
+	}
+	return text
+}
+
+func formatListenAddr(service Service) string {
+	return net.JoinHostPort(service.LocalAddr, service.LocalPort)
+}
+
+// Emit 记录事件，并按匹配的告警规则发送通知
+func (e *EventEngine) Emit(event Event) {
+	e.mu.Lock()
+	e.nextID++
+	event.ID = e.nextID
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	type dispatch struct {
+		rule      string
+		notifiers []string
+	}
+	var dispatches []dispatch
+
+	for _, rule := range e.rules {
+		if !rule.matches(event) {
+			continue
+		}
+
+		key := rule.Name + "|" + event.Type
+		if event.Service != nil {
+			key += "|" + serviceID(*event.Service)
+		}
+
+		status := e.dispatchStatusLocked(rule.Name, key, rule.debounce, event.Time)
+		if status == eventSent {
+			dispatches = append(dispatches, dispatch{rule: rule.Name, notifiers: rule.Notifiers})
+		}
+		event.Matches = append(event.Matches, EventMatch{Rule: rule.Name, Status: status})
+	}
+
+	e.recordLocked(event)
+	e.mu.Unlock()
+
+	for _, d := range dispatches {
+		notification := event
+		notification.Rule = d.rule
+		notification.Matches = nil
+		e.Notify(notification, d.notifiers)
+	}
+}
+
+// Trigger 记录规则引擎产生的事件，规则未被静默且不在去抖间隔内时发送到指定的通知方式
+func (e *EventEngine) Trigger(event Event, notifiers []string, debounce time.Duration) {
+	e.mu.Lock()
+	e.nextID++
+	event.ID = e.nextID
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	status := e.dispatchStatusLocked(event.Rule, event.Rule+"|"+event.Type, debounce, event.Time)
+	recorded := event
+	recorded.Matches = []EventMatch{{Rule: event.Rule, Status: status}}
+	e.recordLocked(recorded)
+	e.mu.Unlock()
+
+	if status == eventSent {
+		e.Notify(event, notifiers)
+	}
+}
+
+// dispatchStatusLocked 判断规则的事件是否发送：规则被静默，或同一key距上次发送不足debounce时不发送。
+// 需要发送时记录本次发送时间
+func (e *EventEngine) dispatchStatusLocked(rule, key string, debounce time.Duration, t time.Time) string {
+	switch {
+	case e.silencedLocked(rule, t):
+		return eventSilenced
+	case debounce > 0 && t.Sub(e.lastSent[key]) < debounce:
+		return eventDebounced
+	}
+	e.lastSent[key] = t
+	return eventSent
+}
+
+func (e *EventEngine) recordLocked(event Event) {
+	e.events = append(e.events, event)
+	if len(e.events) > maxRecentEvents {
+		e.events = e.events[len(e.events)-maxRecentEvents:]
+	}
+	log.Printf("事件 #%d %s: %s\n", event.ID, event.Type, event.Message)
+}
+
+// HasNotifier 判断是否配置了指定名称的通知方式
+func (e *EventEngine) HasNotifier(name string) bool {
+	_, ok := e.notifiers[name]
+	return ok
+}
+
+// Notify 在后台把事件发送到指定的通知方式，names为空时发送到全部通知方式
+func (e *EventEngine) Notify(event Event, names []string) {
+	if len(names) == 0 {
+		names = e.order
+	}
+	for _, name := range names {
+		notifier, ok := e.notifiers[name]
+		if !ok {
+			log.Printf("未定义的通知方式: %s\n", name)
+			continue
+		}
+		go func(notifier Notifier) {
+			if err := notifier.Notify(event); err != nil {
+				log.Printf("通过 %s 发送事件 #%d 失败: %v\n", notifier.Name(), event.ID, err)
+			} else {
+				log.Printf("已通过 %s 发送事件 #%d\n", notifier.Name(), event.ID)
+			}
+		}(notifier)
+	}
+}
+
+func (e *EventEngine) silencedLocked(rule string, now time.Time) bool {
+	for _, name := range []string{"", rule} {
+		if until, ok := e.silences[name]; ok {
+			if now.Before(until) {
+				return true
+			}
+			delete(e.silences, name)
+		}
+	}
+	return false
+}
+
+// Silence 在指定时间内不发送规则的通知，rule为空表示全部规则，duration小于等于0时取消静默
+func (e *EventEngine) Silence(rule string, duration time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if duration <= 0 {
+		delete(e.silences, rule)
+		return
+	}
+	e.silences[rule] = time.Now().Add(duration)
+}
+
+// 当前生效的静默
+type EventSilence struct {
+	Rule  string    `json:"rule"` // 空表示全部规则
+	Until time.Time `json:"until"`
+}
+
+func (e *EventEngine) Silences() []EventSilence {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := time.Now()
+	silences := []EventSilence{}
+	for rule, until := range e.silences {
+		if now.Before(until) {
+			silences = append(silences, EventSilence{Rule: rule, Until: until})
+		}
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		return silences[i].Rule < silences[j].Rule
+	})
+	return silences
+}
+
+// Events 返回最近的事件，最新的在前
+func (e *EventEngine) Events(limit int) []Event {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if limit <= 0 || limit > len(e.events) {
+		limit = len(e.events)
+	}
+	events := make([]Event, 0, limit)
+	for i := len(e.events) - 1; i >= 0 && len(events) < limit; i-- {
+		events = append(events, e.events[i])
+	}
+	return events
+}
+
+// 最近的事件，?limit= 限制数量
+func eventsHandler(engine *EventEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		events := engine.Events(limit)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+	}
+}
+
+// 静默告警规则：GET返回当前的静默，POST {"rule": "名称", "duration": "1h"} 设置静默，
+// duration为空或"0"时取消静默
+// silenceAllowed 检查静默请求的来源：配置了令牌时要求 Authorization: Bearer <令牌>，
+// 否则只接受本机（回环地址）发来的请求
+func (e *EventEngine) silenceAllowed(r *http.Request) bool {
+	if e.silenceToken != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return ok && subtle.ConstantTimeCompare([]byte(token), []byte(e.silenceToken)) == 1
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func eventSilenceHandler(engine *EventEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(engine.Silences())
+			return
+		case http.MethodPost:
+		default:
+			http.Error(w, "只支持GET和POST方法", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !engine.silenceAllowed(r) {
+			log.Printf("拒绝来自 %s 的静默请求\n", r.RemoteAddr)
+			http.Error(w, "没有权限静默告警", http.StatusForbidden)
+			return
+		}
+
+		var data struct {
+			Rule     string `json:"rule"`
+			Duration string `json:"duration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			log.Printf("解析JSON数据失败: %v\n", err)
+			http.Error(w, "无效的JSON数据", http.StatusBadRequest)
+			return
+		}
+
+		var duration time.Duration
+		if data.Duration != "" {
+			d, err := time.ParseDuration(data.Duration)
+			if err != nil {
+				http.Error(w, "无效的duration: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			duration = d
+		}
+
+		engine.Silence(data.Rule, duration)
+		if duration > 0 {
+			log.Printf("静默告警规则 %q %s\n", data.Rule, duration)
+		} else {
+			log.Printf("取消静默告警规则 %q\n", data.Rule)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(engine.Silences())
+	}
+}
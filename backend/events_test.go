@@ -0,0 +1,89 @@
+package backend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func silenceRequest(remoteAddr, token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/events/silence", strings.NewReader(`{"rule": "", "duration": "1h"}`))
+	r.RemoteAddr = remoteAddr
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestEventSilenceHandlerAccess(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		remoteAddr string
+		token      string
+		want       int
+	}{
+		{"未配置令牌的本机请求", "", "127.0.0.1:40000", "", http.StatusOK},
+		{"未配置令牌的IPv6本机请求", "", "[::1]:40000", "", http.StatusOK},
+		{"未配置令牌的远程请求", "", "192.0.2.10:40000", "", http.StatusForbidden},
+		{"携带正确令牌", "secret", "192.0.2.10:40000", "secret", http.StatusOK},
+		{"携带错误令牌", "secret", "192.0.2.10:40000", "wrong", http.StatusForbidden},
+		{"配置令牌后本机请求也需要令牌", "secret", "127.0.0.1:40000", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		engine := NewEventEngine(AlertConfig{SilenceToken: tt.configured})
+		w := httptest.NewRecorder()
+		eventSilenceHandler(engine)(w, silenceRequest(tt.remoteAddr, tt.token))
+		if w.Code != tt.want {
+			t.Errorf("%s: 状态码 = %d, want %d", tt.name, w.Code, tt.want)
+		}
+		if silenced := len(engine.Silences()) > 0; silenced != (tt.want == http.StatusOK) {
+			t.Errorf("%s: 静默是否生效 = %v", tt.name, silenced)
+		}
+	}
+
+	// 查看静默列表不需要权限
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/api/events/silence", nil)
+	r.RemoteAddr = "192.0.2.10:40000"
+	eventSilenceHandler(NewEventEngine(AlertConfig{}))(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("GET 状态码 = %d, want 200", w.Code)
+	}
+}
+
+func TestEventTriggerDebounce(t *testing.T) {
+	engine := NewEventEngine(AlertConfig{})
+	start := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+
+	trigger := func(eventType string, offset time.Duration) string {
+		engine.Trigger(Event{Type: eventType, Rule: "db-exposed", Time: start.Add(offset)}, nil, 10*time.Minute)
+		return engine.Events(1)[0].Matches[0].Status
+	}
+
+	steps := []struct {
+		eventType string
+		offset    time.Duration
+		want      string
+	}{
+		{eventRuleFiring, 0, eventSent},
+		{eventRuleResolved, time.Minute, eventSent},
+		{eventRuleFiring, 2 * time.Minute, eventDebounced}, // 规则反复触发和恢复时不重复通知
+		{eventRuleResolved, 3 * time.Minute, eventDebounced},
+		{eventRuleFiring, 11 * time.Minute, eventSent},
+	}
+	for _, step := range steps {
+		if got := trigger(step.eventType, step.offset); got != step.want {
+			t.Errorf("%s +%s: %s, want %s", step.eventType, step.offset, got, step.want)
+		}
+	}
+
+	// 静默优先于去抖
+	engine.Silence("db-exposed", time.Hour)
+	engine.Trigger(Event{Type: eventRuleResolved, Rule: "db-exposed", Time: time.Now()}, nil, 0)
+	if got := engine.Events(1)[0].Matches[0].Status; got != eventSilenced {
+		t.Errorf("静默期间: %s, want %s", got, eventSilenced)
+	}
+}
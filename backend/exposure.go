@@ -0,0 +1,299 @@
+package backend
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 监听的暴露范围
+const (
+	exposureLoopback = "loopback" // 只能从本机访问
+	exposurePrivate  = "private"  // 可以从内网访问
+	exposurePublic   = "public"   // 可以从公网访问
+)
+
+// 风险等级
+const (
+	severityNone   = "none"
+	severityLow    = "low"
+	severityMedium = "medium"
+	severityHigh   = "high"
+)
+
+// 公网IP的刷新间隔，获取失败时较快重试
+const (
+	publicIPCacheTTL      = 10 * time.Minute
+	publicIPRetryInterval = time.Minute
+	publicIPTimeout       = 5 * time.Second
+)
+
+// 不应暴露到公网的端口：数据库、缓存、管理接口等
+var sensitivePorts = map[int]string{
+	23:    "Telnet",
+	111:   "rpcbind",
+	135:   "MSRPC",
+	139:   "NetBIOS",
+	445:   "SMB",
+	1433:  "SQL Server",
+	1521:  "Oracle",
+	2049:  "NFS",
+	2375:  "Docker API",
+	2379:  "etcd",
+	3306:  "MySQL",
+	3389:  "RDP",
+	5432:  "PostgreSQL",
+	5601:  "Kibana",
+	5900:  "VNC",
+	5984:  "CouchDB",
+	6379:  "Redis",
+	8086:  "InfluxDB",
+	9042:  "Cassandra",
+	9200:  "Elasticsearch",
+	10250: "kubelet",
+	11211: "Memcached",
+	27017: "MongoDB",
+}
+
+// 监听的暴露分析结果
+type Exposure struct {
+	Class     string   `json:"class"`    // loopback, private, public
+	Severity  string   `json:"severity"` // none, low, medium, high
+	Sensitive string   `json:"sensitive,omitempty"`
+	Addresses []string `json:"addresses,omitempty"` // 可以访问该监听的本机地址
+	Reason    string   `json:"reason"`
+}
+
+// 暴露分析的汇总
+type ExposureReport struct {
+	Time     time.Time      `json:"time"`
+	PublicIP string         `json:"public_ip,omitempty"`
+	Counts   map[string]int `json:"counts"` // 按风险等级统计
+	Services []Service      `json:"services"`
+}
+
+// ExposureAnalyzer 根据监听地址、网卡地址和公网IP判断监听的暴露范围
+type ExposureAnalyzer struct {
+	fetch func() (string, error) // 获取公网IP，默认为 getPublicIP
+
+	mu         sync.Mutex
+	publicIP   string
+	fetchError error
+}
+
+func NewExposureAnalyzer() *ExposureAnalyzer {
+	return &ExposureAnalyzer{fetch: getPublicIP}
+}
+
+// Start 在后台获取公网IP，之后定期刷新，获取失败时较快重试
+func (a *ExposureAnalyzer) Start() {
+	go func() {
+		for {
+			if err := a.refresh(); err != nil {
+				time.Sleep(publicIPRetryInterval)
+			} else {
+				time.Sleep(publicIPCacheTTL)
+			}
+		}
+	}()
+}
+
+// 获取一次公网IP并更新缓存，请求期间不持有锁
+func (a *ExposureAnalyzer) refresh() error {
+	ip, err := a.fetch()
+	if err == nil && net.ParseIP(ip) == nil {
+		err = &net.ParseError{Type: "IP address", Text: ip}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err != nil {
+		if a.fetchError == nil {
+			log.Printf("获取公网IP失败，暴露分析只使用网卡地址: %v\n", err)
+		}
+		// 刷新失败时保留上一次获取到的地址
+		a.fetchError = err
+		return err
+	}
+	a.publicIP, a.fetchError = ip, nil
+	return nil
+}
+
+// 缓存的公网IP，还没有获取到时为空
+func (a *ExposureAnalyzer) cachedPublicIP() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.publicIP
+}
+
+func addressClass(ip net.IP) string {
+	switch {
+	case ip.IsLoopback():
+		return exposureLoopback
+	case ip.IsPrivate(), ip.IsLinkLocalUnicast(), isSharedAddress(ip):
+		return exposurePrivate
+	case ip.IsGlobalUnicast():
+		return exposurePublic
+	}
+	return exposurePrivate
+}
+
+// 运营商级NAT地址 100.64.0.0/10
+func isSharedAddress(ip net.IP) bool {
+	ip4 := ip.To4()
+	return ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64
+}
+
+// 本机全部网卡地址
+func localAddresses() []net.IP {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		log.Printf("获取网卡地址失败: %v\n", err)
+		return nil
+	}
+	var ips []net.IP
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok {
+			ips = append(ips, ipNet.IP)
+		}
+	}
+	return ips
+}
+
+// Analyze 分析每个服务的暴露范围并标注在服务上
+func (a *ExposureAnalyzer) Analyze(services []Service) string {
+	publicIP := a.cachedPublicIP()
+	addrs := localAddresses()
+	for i := range services {
+		services[i].Exposure = analyzeExposure(services[i], addrs, publicIP)
+	}
+	return publicIP
+}
+
+func analyzeExposure(service Service, localAddrs []net.IP, publicIP string) *Exposure {
+	exposure := &Exposure{Class: exposurePrivate}
+	viaNAT := false
+	port, _ := strconv.Atoi(service.LocalPort)
+	exposure.Sensitive = sensitivePorts[port]
+
+	addr := normalizeAddr(service.LocalAddr)
+	switch {
+	case service.Netns != "" && service.Netns != "host":
+		// 其他网络命名空间中的监听只能通过端口映射访问，映射会在主机网络命名空间中单独出现
+		exposure.Reason = "监听在网络命名空间 " + service.Netns + " 中"
+	case isWildcardAddr(addr):
+		ipv4Only := addr == "0.0.0.0"
+		for _, ip := range localAddrs {
+			if ipv4Only && ip.To4() == nil {
+				continue
+			}
+			class := addressClass(ip)
+			if class == exposureLoopback {
+				continue
+			}
+			exposure.Addresses = append(exposure.Addresses, ip.String())
+			if class == exposurePublic {
+				exposure.Class = exposurePublic
+			}
+		}
+		switch {
+		case exposure.Class == exposurePublic:
+			exposure.Reason = "监听全部地址，本机网卡有公网地址"
+		case publicIP != "":
+			// 公网IP不在本机网卡上：主机位于NAT之后，端口可能被映射到公网
+			exposure.Class = exposurePublic
+			exposure.Addresses = append(exposure.Addresses, publicIP)
+			viaNAT = true
+			exposure.Reason = "监听全部地址，主机可以通过公网IP " + publicIP + " 访问（NAT）"
+		default:
+			exposure.Reason = "监听全部地址，本机只有内网地址"
+		}
+	default:
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			exposure.Reason = "无法解析的监听地址"
+			break
+		}
+		exposure.Class = addressClass(ip)
+		exposure.Addresses = []string{ip.String()}
+		switch exposure.Class {
+		case exposureLoopback:
+			exposure.Reason = "只监听回环地址"
+		case exposurePublic:
+			exposure.Reason = "监听公网地址"
+		default:
+			exposure.Reason = "只监听内网地址"
+		}
+	}
+
+	exposure.Severity = exposureSeverity(exposure, viaNAT)
+	return exposure
+}
+
+// 风险等级：敏感端口直接暴露到公网为high，经NAT可能暴露为medium，
+// 其他端口暴露到公网或敏感端口暴露到内网为low
+func exposureSeverity(exposure *Exposure, viaNAT bool) string {
+	sensitive := exposure.Sensitive != ""
+	switch exposure.Class {
+	case exposurePublic:
+		switch {
+		case !sensitive:
+			return severityLow
+		case viaNAT:
+			return severityMedium
+		}
+		return severityHigh
+	case exposurePrivate:
+		if sensitive {
+			return severityLow
+		}
+	}
+	return severityNone
+}
+
+var severityRank = map[string]int{severityNone: 0, severityLow: 1, severityMedium: 2, severityHigh: 3}
+
+// Report 分析当前的服务列表，按风险等级从高到低排列
+func (a *ExposureAnalyzer) Report(services []Service) ExposureReport {
+	report := ExposureReport{
+		Time:     time.Now(),
+		Counts:   map[string]int{severityNone: 0, severityLow: 0, severityMedium: 0, severityHigh: 0},
+		Services: services,
+	}
+	report.PublicIP = a.Analyze(services)
+	for _, service := range services {
+		report.Counts[service.Exposure.Severity]++
+	}
+	sort.SliceStable(report.Services, func(i, j int) bool {
+		ri, rj := severityRank[services[i].Exposure.Severity], severityRank[services[j].Exposure.Severity]
+		if ri != rj {
+			return ri > rj
+		}
+		return serviceLess(services[i], services[j])
+	})
+	return report
+}
+
+// 暴露分析结果
+func exposureHandler(analyzer *ExposureAnalyzer, sampler *Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到获取暴露分析的请求")
+		snapshot, err := currentSnapshot(sampler)
+		if err != nil {
+			log.Printf("获取服务信息失败: %v\n", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		report := analyzer.Report(append([]Service(nil), snapshot.Services...))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+		log.Printf("暴露分析: 高风险 %d, 中风险 %d, 低风险 %d\n",
+			report.Counts[severityHigh], report.Counts[severityMedium], report.Counts[severityLow])
+	}
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExposurePublicIPRefreshDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	analyzer := &ExposureAnalyzer{fetch: func() (string, error) {
+		<-release
+		return "203.0.113.7", nil
+	}}
+
+	done := make(chan error)
+	go func() { done <- analyzer.refresh() }()
+
+	// 请求公网IP期间，读取缓存和分析服务不应等待
+	read := make(chan string)
+	go func() {
+		analyzer.Analyze([]Service{{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "22"}})
+		read <- analyzer.cachedPublicIP()
+	}()
+	select {
+	case ip := <-read:
+		if ip != "" {
+			t.Errorf("获取到公网IP之前 cachedPublicIP() = %q, want 空", ip)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("获取公网IP期间读取缓存被阻塞")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if ip := analyzer.cachedPublicIP(); ip != "203.0.113.7" {
+		t.Errorf("cachedPublicIP() = %q, want 203.0.113.7", ip)
+	}
+}
+
+func TestExposurePublicIPRefreshFailure(t *testing.T) {
+	results := []struct {
+		ip  string
+		err error
+	}{
+		{"203.0.113.7", nil},
+		{"", errors.New("timeout")},
+		{"<html>", nil},
+	}
+	analyzer := &ExposureAnalyzer{fetch: func() (string, error) {
+		r := results[0]
+		results = results[1:]
+		return r.ip, r.err
+	}}
+
+	if err := analyzer.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	// 刷新失败或返回的不是IP地址时保留上一次的结果
+	for i := 0; i < 2; i++ {
+		if err := analyzer.refresh(); err == nil {
+			t.Error("refresh() 应该返回错误")
+		}
+		if ip := analyzer.cachedPublicIP(); ip != "203.0.113.7" {
+			t.Errorf("cachedPublicIP() = %q, want 203.0.113.7", ip)
+		}
+	}
+}
+
+func TestInterfacesHandlerUsesCachedPublicIP(t *testing.T) {
+	// 请求公网IP的服务不可用时，接口列表也不应等待
+	analyzer := &ExposureAnalyzer{fetch: func() (string, error) {
+		select {}
+	}}
+	analyzer.publicIP = "203.0.113.7"
+
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		interfacesHandler(analyzer)(rec, httptest.NewRequest(http.MethodGet, "/api/interfaces", nil))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("获取网络接口列表时不应请求公网IP")
+	}
+
+	var interfaces []InterfaceInfo
+	if err := json.NewDecoder(rec.Body).Decode(&interfaces); err != nil {
+		t.Fatal(err)
+	}
+	if len(interfaces) == 0 || interfaces[len(interfaces)-1] != (InterfaceInfo{Name: "公网", IP: "203.0.113.7"}) {
+		t.Errorf("interfaces = %+v, want 最后一项为缓存的公网IP", interfaces)
+	}
+}
@@ -0,0 +1,569 @@
+package backend
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 规则条件使用的简单表达式语言，例如：
+//
+//	port == 22 && process != "sshd"
+//	port in [3306, 5432, 6379] && !loopback
+//	name matches "^eth[0-9]+$"
+//
+// 支持 == != < <= > >=、in [列表]、matches 正则、contains 子串、&& || !、负数（-1）和括号。
+// 字段取值由 exprFields 提供，数值与字符串比较时按数值比较
+
+// 表达式中字段的取值：string、float64 或 bool
+type exprFields map[string]interface{}
+
+type exprNode interface {
+	eval(fields exprFields) (interface{}, error)
+}
+
+// 编译后的表达式
+type Expr struct {
+	source string
+	root   exprNode
+}
+
+func (e *Expr) String() string { return e.source }
+
+// Eval 计算表达式，结果必须为布尔值
+func (e *Expr) Eval(fields exprFields) (bool, error) {
+	value, err := e.root.eval(fields)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("表达式的结果不是布尔值: %v", value)
+	}
+	return result, nil
+}
+
+// CompileExpr 解析表达式，fields 为允许使用的字段名
+func CompileExpr(source string, fields []string) (*Expr, error) {
+	tokens, err := lexExpr(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens, fields: make(map[string]bool)}
+	for _, field := range fields {
+		p.fields[field] = true
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("位置 %d 处多余的 %q", tok.pos, tok.text)
+	}
+	return &Expr{source: source, root: root}, nil
+}
+
+// 词法分析
+
+const (
+	tokenEOF = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+type exprToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+func lexExpr(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	runes := []rune(source)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenIdent, text: string(runes[start:i]), pos: start})
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, exprToken{kind: tokenNumber, text: string(runes[start:i]), pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			var text strings.Builder
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("位置 %d 处的字符串没有结束", start)
+			}
+			i++
+			tokens = append(tokens, exprToken{kind: tokenString, text: text.String(), pos: start})
+		default:
+			op := ""
+			if i+1 < len(runes) {
+				switch two := string(runes[i : i+2]); two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "" {
+				switch r {
+				case '<', '>', '!', '-', '(', ')', '[', ']', ',':
+					op = string(r)
+				default:
+					return nil, fmt.Errorf("位置 %d 处无效的字符 %q", i, r)
+				}
+			}
+			tokens = append(tokens, exprToken{kind: tokenOp, text: op, pos: i})
+			i += len([]rune(op))
+		}
+	}
+	return append(tokens, exprToken{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+// 语法分析
+
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	fields map[string]bool
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) accept(kind int, text string) bool {
+	tok := p.peek()
+	if tok.kind == kind && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(text string) error {
+	if !p.accept(tokenOp, text) {
+		tok := p.peek()
+		if tok.kind == tokenEOF {
+			return fmt.Errorf("表达式不完整，缺少 %q", text)
+		}
+		return fmt.Errorf("位置 %d 处应为 %q，实际为 %q", tok.pos, text, tok.text)
+	}
+	return nil
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.accept(tokenOp, "!") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{operand: operand}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch {
+	case tok.kind == tokenOp && (tok.text == "==" || tok.text == "!=" || tok.text == "<" || tok.text == "<=" || tok.text == ">" || tok.text == ">="):
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: tok.text, left: left, right: right}, nil
+	case tok.kind == tokenIdent && tok.text == "in":
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &inNode{left: left, list: right}, nil
+	case tok.kind == tokenIdent && tok.text == "contains":
+		p.next()
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &containsNode{left: left, right: right}, nil
+	case tok.kind == tokenIdent && tok.text == "matches":
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, fmt.Errorf("位置 %d 处 matches 后应为字符串", pattern.pos)
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return nil, fmt.Errorf("无效的正则表达式 %q: %v", pattern.text, err)
+		}
+		return &matchNode{left: left, re: re}, nil
+	}
+	return left, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+	case tokenNumber:
+		n, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("位置 %d 处无效的数字 %q", tok.pos, tok.text)
+		}
+		return &literalNode{value: n}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		}
+		if !p.fields[tok.text] {
+			return nil, fmt.Errorf("位置 %d 处未知的字段 %q", tok.pos, tok.text)
+		}
+		return &fieldNode{name: tok.text}, nil
+	case tokenOp:
+		switch tok.text {
+		case "-":
+			operand, err := p.parsePrimary()
+			if err != nil {
+				return nil, err
+			}
+			if literal, ok := operand.(*literalNode); ok {
+				if n, ok := literal.value.(float64); ok {
+					return &literalNode{value: -n}, nil
+				}
+			}
+			return &negNode{operand: operand}, nil
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			list := &listNode{}
+			if p.accept(tokenOp, "]") {
+				return list, nil
+			}
+			for {
+				item, err := p.parsePrimary()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept(tokenOp, ",") {
+					continue
+				}
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+				return list, nil
+			}
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("表达式不完整")
+	}
+	return nil, fmt.Errorf("位置 %d 处无效的 %q", tok.pos, tok.text)
+}
+
+// 语法树节点
+
+type literalNode struct{ value interface{} }
+
+func (n *literalNode) eval(exprFields) (interface{}, error) { return n.value, nil }
+
+type fieldNode struct{ name string }
+
+func (n *fieldNode) eval(fields exprFields) (interface{}, error) {
+	value, ok := fields[n.name]
+	if !ok {
+		return "", nil
+	}
+	return value, nil
+}
+
+type listNode struct{ items []exprNode }
+
+func (n *listNode) eval(fields exprFields) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(fields)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type logicalNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *logicalNode) eval(fields exprFields) (interface{}, error) {
+	left, err := evalBool(n.left, fields)
+	if err != nil {
+		return nil, err
+	}
+	// 短路求值
+	if n.op == "&&" && !left || n.op == "||" && left {
+		return left, nil
+	}
+	return evalBool(n.right, fields)
+}
+
+type notNode struct{ operand exprNode }
+
+func (n *notNode) eval(fields exprFields) (interface{}, error) {
+	value, err := evalBool(n.operand, fields)
+	if err != nil {
+		return nil, err
+	}
+	return !value, nil
+}
+
+type negNode struct{ operand exprNode }
+
+func (n *negNode) eval(fields exprFields) (interface{}, error) {
+	value, err := n.operand.eval(fields)
+	if err != nil {
+		return nil, err
+	}
+	number, ok := exprNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("%v 不是数值", value)
+	}
+	return -number, nil
+}
+
+type compareNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *compareNode) eval(fields exprFields) (interface{}, error) {
+	left, err := n.left.eval(fields)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := compareValues(left, right)
+	if err != nil {
+		if n.op == "==" || n.op == "!=" {
+			// 类型不同的值不相等
+			return n.op == "!=", nil
+		}
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	}
+	return c >= 0, nil
+}
+
+type inNode struct {
+	left, list exprNode
+}
+
+func (n *inNode) eval(fields exprFields) (interface{}, error) {
+	left, err := n.left.eval(fields)
+	if err != nil {
+		return nil, err
+	}
+	list, err := n.list.eval(fields)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := list.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("in 后应为列表")
+	}
+	for _, item := range items {
+		if c, err := compareValues(left, item); err == nil && c == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type containsNode struct {
+	left, right exprNode
+}
+
+func (n *containsNode) eval(fields exprFields) (interface{}, error) {
+	left, err := n.left.eval(fields)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(fields)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Contains(exprString(left), exprString(right)), nil
+}
+
+type matchNode struct {
+	left exprNode
+	re   *regexp.Regexp
+}
+
+func (n *matchNode) eval(fields exprFields) (interface{}, error) {
+	left, err := n.left.eval(fields)
+	if err != nil {
+		return nil, err
+	}
+	return n.re.MatchString(exprString(left)), nil
+}
+
+func evalBool(node exprNode, fields exprFields) (bool, error) {
+	value, err := node.eval(fields)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%v 不是布尔值", value)
+	}
+	return b, nil
+}
+
+func exprString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+func exprNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// 比较两个值：任一方为数值时按数值比较，否则按字符串比较
+func compareValues(left, right interface{}) (int, error) {
+	_, leftNumber := left.(float64)
+	_, rightNumber := right.(float64)
+	if leftNumber || rightNumber {
+		a, okA := exprNumber(left)
+		b, okB := exprNumber(right)
+		if !okA || !okB {
+			return 0, fmt.Errorf("无法将 %v 与 %v 按数值比较", left, right)
+		}
+		switch {
+		case a < b:
+			return -1, nil
+		case a > b:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	leftBool, okA := left.(bool)
+	rightBool, okB := right.(bool)
+	if okA || okB {
+		if !okA || !okB {
+			return 0, fmt.Errorf("无法比较 %v 与 %v", left, right)
+		}
+		if leftBool == rightBool {
+			return 0, nil
+		}
+		return 1, nil
+	}
+
+	return strings.Compare(exprString(left), exprString(right)), nil
+}
@@ -0,0 +1,136 @@
+package backend
+
+import (
+	"strings"
+	"testing"
+)
+
+var exprTestFields = []string{"port", "protocol", "process", "uid", "loopback", "cmdline"}
+
+func exprTestValues() exprFields {
+	return exprFields{
+		"port":     float64(22),
+		"protocol": "tcp",
+		"process":  "sshd",
+		"uid":      float64(-1),
+		"loopback": false,
+		"cmdline":  "/usr/sbin/sshd -D",
+	}
+}
+
+func TestExprEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// 比较
+		{`port == 22`, true},
+		{`port != 22`, false},
+		{`port < 1024`, true},
+		{`port >= 22 && port <= 22`, true},
+		{`port > 22`, false},
+		{`protocol == "tcp"`, true},
+		{`protocol == 'udp'`, false},
+		{`loopback == false`, true},
+
+		// 数值与字符串比较时按数值比较
+		{`port == "22"`, true},
+		{`port == "022"`, true},
+		{`port < "100"`, true},
+		{`"9" < "10"`, false},
+		{`port == "ssh"`, false},
+		{`port != "ssh"`, true},
+
+		// 负数
+		{`uid == -1`, true},
+		{`uid < 0`, true},
+		{`-uid == 1`, true},
+		{`port in [-1, 22]`, true},
+		{`uid != - 1`, false},
+
+		// in / matches / contains
+		{`port in [22, 80, 443]`, true},
+		{`port in ["22"]`, true},
+		{`port in []`, false},
+		{`protocol in ["udp", "tcp"]`, true},
+		{`process matches "^ssh"`, true},
+		{`process matches "^nginx$"`, false},
+		{`cmdline contains "-D"`, true},
+		{`cmdline contains "nginx"`, false},
+		{`port contains "2"`, true},
+
+		// 优先级：&& 高于 ||，! 只作用于紧随其后的操作数
+		{`port == 80 || port == 22 && protocol == "tcp"`, true},
+		{`port == 22 || port == 80 && protocol == "udp"`, true},
+		{`(port == 22 || port == 80) && protocol == "udp"`, false},
+		{`!loopback && port == 22`, true},
+		{`!(port == 22)`, false},
+		{`!!loopback`, false},
+		{`true || false && false`, true},
+	}
+	for _, tt := range tests {
+		expr, err := CompileExpr(tt.expr, exprTestFields)
+		if err != nil {
+			t.Errorf("CompileExpr(%q): %v", tt.expr, err)
+			continue
+		}
+		got, err := expr.Eval(exprTestValues())
+		if err != nil {
+			t.Errorf("Eval(%q): %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestExprParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string // 错误信息中应包含的内容
+	}{
+		{`port ==`, "不完整"},
+		{`port == 22 &&`, "不完整"},
+		{`(port == 22`, `缺少 ")"`},
+		{`port in [22, 80`, `缺少 "]"`},
+		{`port == 22)`, "多余的"},
+		{`name == "eth0"`, `未知的字段 "name"`},
+		{`process == "sshd`, "没有结束"},
+		{`port = 22`, "无效的字符"},
+		{`process matches sshd`, "应为字符串"},
+		{`process matches "("`, "无效的正则表达式"},
+		{`port == 1.2.3`, "无效的数字"},
+		{`port == -`, "不完整"},
+		{`port == ]`, "无效的"},
+	}
+	for _, tt := range tests {
+		_, err := CompileExpr(tt.expr, exprTestFields)
+		if err == nil {
+			t.Errorf("CompileExpr(%q) 应该返回错误", tt.expr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("CompileExpr(%q) = %v, want 包含 %q", tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestExprEvalErrors(t *testing.T) {
+	for _, source := range []string{
+		`port`,             // 结果不是布尔值
+		`port && loopback`, // && 的操作数不是布尔值
+		`port < "ssh"`,     // 无法按数值比较
+		`port in protocol`, // in 后不是列表
+		`-process == 1`,    // 字符串取负
+	} {
+		expr, err := CompileExpr(source, exprTestFields)
+		if err != nil {
+			t.Errorf("CompileExpr(%q): %v", source, err)
+			continue
+		}
+		if _, err := expr.Eval(exprTestValues()); err == nil {
+			t.Errorf("Eval(%q) 应该返回错误", source)
+		}
+	}
+}
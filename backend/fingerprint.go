@@ -0,0 +1,482 @@
+package backend
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 识别结果的来源
+const (
+	fingerprintPort   = "port"   // 只根据端口注册表
+	fingerprintBanner = "banner" // 根据服务返回的欢迎信息或握手响应
+)
+
+const (
+	fingerprintInterval   = time.Minute
+	fingerprintTimeout    = 1500 * time.Millisecond
+	fingerprintMaxBanner  = 256 // 保存的欢迎信息最大长度
+	fingerprintReadBuffer = 4096
+)
+
+// 服务的识别结果
+type Fingerprint struct {
+	Service  string    `json:"service,omitempty"`  // 端口注册表中的服务名称
+	Protocol string    `json:"protocol,omitempty"` // 实际识别到的应用层协议，例如 ssh、http、redis
+	TLS      bool      `json:"tls,omitempty"`      // 协议运行在TLS之上
+	Product  string    `json:"product,omitempty"`  // 例如 OpenSSH、nginx、MariaDB
+	Version  string    `json:"version,omitempty"`
+	Banner   string    `json:"banner,omitempty"`
+	Source   string    `json:"source"` // port, banner
+	Time     time.Time `json:"time,omitempty"`
+}
+
+// 主动探测：在新的连接上发送请求并解析响应，无法识别时返回nil
+type fingerprintProbe struct {
+	protocol string
+	run      func(conn net.Conn) *Fingerprint
+}
+
+var fingerprintProbes = []fingerprintProbe{
+	{"postgresql", probePostgreSQL},
+	{"http", probeHTTPServer},
+	{"redis", probeRedis},
+}
+
+// Fingerprinter 在后台识别监听上运行的协议和版本
+type Fingerprinter struct {
+	registry *PortRegistry
+	sampler  *Sampler
+
+	ready chan struct{} // 第一轮识别完成后关闭
+
+	mu      sync.RWMutex
+	results map[string]*Fingerprint // 服务ID -> 识别结果
+	owners  map[string]string       // 服务ID -> 识别时的进程，进程变化后重新识别
+}
+
+func NewFingerprinter(registry *PortRegistry, sampler *Sampler) *Fingerprinter {
+	return &Fingerprinter{
+		registry: registry,
+		sampler:  sampler,
+		ready:    make(chan struct{}),
+		results:  make(map[string]*Fingerprint),
+		owners:   make(map[string]string),
+	}
+}
+
+// Ready 返回的通道在第一轮识别完成（包括获取服务列表失败）后关闭
+func (f *Fingerprinter) Ready() <-chan struct{} {
+	return f.ready
+}
+
+// Start 在后台定期识别新出现的监听
+func (f *Fingerprinter) Start() {
+	go func() {
+		f.scan()
+		close(f.ready)
+		ticker := time.NewTicker(fingerprintInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			f.scan()
+		}
+	}()
+}
+
+func serviceOwner(service Service) string {
+	return serviceProgram(service) + "|" + service.PID
+}
+
+func (f *Fingerprinter) scan() {
+	snapshot, err := currentSnapshot(f.sampler)
+	if err != nil {
+		log.Printf("获取服务列表失败，跳过本次协议识别: %v\n", err)
+		return
+	}
+
+	// 只识别本机网络命名空间中新出现或换了进程的TCP监听
+	var pending []Service
+	present := make(map[string]bool)
+	f.mu.RLock()
+	for _, service := range snapshot.Services {
+		if service.Protocol != "tcp" || (service.Netns != "" && service.Netns != "host") {
+			continue
+		}
+		id := serviceID(service)
+		present[id] = true
+		if owner, ok := f.owners[id]; !ok || owner != serviceOwner(service) {
+			pending = append(pending, service)
+		}
+	}
+	f.mu.RUnlock()
+
+	results := make([]*Fingerprint, len(pending))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for i, service := range pending {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, service Service) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = f.Identify(service)
+		}(i, service)
+	}
+	wg.Wait()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, service := range pending {
+		id := serviceID(service)
+		f.owners[id] = serviceOwner(service)
+		if results[i] != nil {
+			f.results[id] = results[i]
+		} else {
+			delete(f.results, id)
+		}
+	}
+	for id := range f.owners {
+		if !present[id] {
+			delete(f.owners, id)
+			delete(f.results, id)
+		}
+	}
+}
+
+// Identify 连接服务识别协议：先等待服务端主动发送的欢迎信息，
+// 没有时依次尝试端口对应的协议和其他常见协议的握手，最后尝试TLS
+func (f *Fingerprinter) Identify(service Service) *Fingerprint {
+	address := net.JoinHostPort(probeHost(service.LocalAddr), service.LocalPort)
+
+	fp, silent := grabBanner(address)
+	if fp != nil || !silent {
+		return fp
+	}
+
+	probes := fingerprintProbes
+	if entry, ok := f.registry.Lookup(service.LocalPort, service.Protocol); ok {
+		// 端口注册表中的协议优先尝试
+		probes = orderProbes(entry.Name)
+	}
+	for _, probe := range probes {
+		if fp := runProbe(address, probe, false); fp != nil {
+			return fp
+		}
+	}
+
+	// TLS握手成功后在TLS之上再尝试HTTP
+	if fp := runProbe(address, fingerprintProbe{"http", probeHTTPServer}, true); fp != nil {
+		return fp
+	}
+	if conn, err := dialFingerprint(address, true); err == nil {
+		conn.Close()
+		return &Fingerprint{Protocol: "tls", TLS: true, Source: fingerprintBanner, Time: time.Now()}
+	}
+	return nil
+}
+
+func orderProbes(name string) []fingerprintProbe {
+	name = strings.ToLower(name)
+	ordered := make([]fingerprintProbe, 0, len(fingerprintProbes))
+	for _, probe := range fingerprintProbes {
+		if strings.HasPrefix(name, probe.protocol) || (probe.protocol == "postgresql" && name == "postgres") {
+			ordered = append([]fingerprintProbe{probe}, ordered...)
+		} else {
+			ordered = append(ordered, probe)
+		}
+	}
+	return ordered
+}
+
+func dialFingerprint(address string, useTLS bool) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: fingerprintTimeout}
+	if useTLS {
+		return tls.DialWithDialer(dialer, "tcp", address, &tls.Config{InsecureSkipVerify: true})
+	}
+	return dialer.Dial("tcp", address)
+}
+
+func runProbe(address string, probe fingerprintProbe, useTLS bool) *Fingerprint {
+	conn, err := dialFingerprint(address, useTLS)
+	if err != nil {
+		return nil
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(fingerprintTimeout))
+
+	fp := probe.run(conn)
+	if fp != nil {
+		fp.TLS = useTLS
+		fp.Source = fingerprintBanner
+		fp.Time = time.Now()
+		fp.sanitize()
+	}
+	return fp
+}
+
+// 读取服务端主动发送的欢迎信息。silent 表示连接成功但服务端没有发送任何数据
+func grabBanner(address string) (fp *Fingerprint, silent bool) {
+	conn, err := dialFingerprint(address, false)
+	if err != nil {
+		return nil, false
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(fingerprintTimeout))
+
+	buf := make([]byte, fingerprintReadBuffer)
+	n, err := conn.Read(buf)
+	if n == 0 {
+		// 超时说明服务在等待客户端先发送数据，连接被关闭时也继续尝试主动探测
+		return nil, true
+	}
+
+	fp = parseGreeting(buf[:n])
+	fp.Source = fingerprintBanner
+	fp.Time = time.Now()
+	fp.sanitize()
+	return fp, false
+}
+
+// 解析服务端主动发送的欢迎信息：SSH、MySQL、SMTP、FTP等
+func parseGreeting(data []byte) *Fingerprint {
+	fp := &Fingerprint{}
+
+	switch {
+	case bytes.HasPrefix(data, []byte("SSH-")):
+		// SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13
+		line := firstLine(data)
+		fp.Protocol = "ssh"
+		fp.Banner = line
+		parts := strings.SplitN(line, "-", 3)
+		if len(parts) == 3 {
+			if software := strings.Fields(parts[2]); len(software) > 0 {
+				fp.Product, fp.Version, _ = strings.Cut(software[0], "_")
+			}
+		}
+		return fp
+	case isMySQLGreeting(data):
+		fp.Protocol = "mysql"
+		fp.Product = "MySQL"
+		if data[4] == 0x0a {
+			version := data[5:]
+			if i := bytes.IndexByte(version, 0); i >= 0 {
+				version = version[:i]
+			}
+			fp.Version = string(version)
+			if strings.Contains(strings.ToLower(fp.Version), "mariadb") {
+				fp.Product = "MariaDB"
+				// 5.5.5-10.11.6-MariaDB-0+deb12u1
+				fp.Version = strings.TrimPrefix(fp.Version, "5.5.5-")
+				fp.Version, _, _ = strings.Cut(fp.Version, "-MariaDB")
+			}
+		} else {
+			// 0xff: 服务端拒绝连接，例如 Host is not allowed
+			fp.Banner = printableBanner(data[7:])
+		}
+		return fp
+	}
+
+	line := firstLine(data)
+	fp.Banner = printableBanner([]byte(line))
+	upper := strings.ToUpper(line)
+	switch {
+	case strings.HasPrefix(line, "220") && strings.Contains(upper, "FTP"):
+		fp.Protocol = "ftp"
+	case strings.HasPrefix(line, "220") && strings.Contains(upper, "SMTP"):
+		fp.Protocol = "smtp"
+	case strings.HasPrefix(line, "+OK"):
+		fp.Protocol = "pop3"
+	case strings.HasPrefix(line, "* OK"):
+		fp.Protocol = "imap"
+	}
+	return fp
+}
+
+// MySQL握手包：3字节长度、序号0、协议版本10；或错误包0xff
+func isMySQLGreeting(data []byte) bool {
+	if len(data) < 5 || data[3] != 0 {
+		return false
+	}
+	// 握手包通常不到200字节
+	length := int(data[0]) | int(data[1])<<8 | int(data[2])<<16
+	if length == 0 || length > 1024 {
+		return false
+	}
+	return data[4] == 0x0a || (data[4] == 0xff && len(data) > 7)
+}
+
+func firstLine(data []byte) string {
+	line, _, _ := strings.Cut(string(data), "\n")
+	return strings.TrimRight(line, "\r")
+}
+
+// 产品、版本和欢迎信息都来自服务端的响应，去掉其中的控制字符并限制长度
+func (fp *Fingerprint) sanitize() {
+	fp.Product = printableBanner([]byte(fp.Product))
+	fp.Version = printableBanner([]byte(fp.Version))
+	fp.Banner = printableBanner([]byte(fp.Banner))
+}
+
+// 只保留可打印的字符并限制长度
+func printableBanner(data []byte) string {
+	var b strings.Builder
+	for _, r := range string(data) {
+		if r >= 0x20 && r != 0x7f && r != 0xfffd {
+			b.WriteRune(r)
+		}
+		if b.Len() >= fingerprintMaxBanner {
+			break
+		}
+	}
+	return b.String()
+}
+
+// PostgreSQL SSLRequest：服务端回复单个字节 S（支持SSL）或 N
+func probePostgreSQL(conn net.Conn) *Fingerprint {
+	if _, err := conn.Write([]byte{0, 0, 0, 8, 0x04, 0xd2, 0x16, 0x2f}); err != nil {
+		return nil
+	}
+	buf := make([]byte, 2)
+	n, _ := conn.Read(buf)
+	if n != 1 || (buf[0] != 'S' && buf[0] != 'N') {
+		return nil
+	}
+	return &Fingerprint{Protocol: "postgresql", Product: "PostgreSQL", Banner: "SSLRequest: " + string(buf[0])}
+}
+
+// HTTP请求，从Server头中获取产品和版本
+func probeHTTPServer(conn net.Conn) *Fingerprint {
+	if _, err := conn.Write([]byte("HEAD / HTTP/1.0\r\nHost: localhost\r\nUser-Agent: port-monitor\r\n\r\n")); err != nil {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return nil
+	}
+	resp.Body.Close()
+
+	fp := &Fingerprint{Protocol: "http", Banner: resp.Proto + " " + resp.Status}
+	if server := resp.Header.Get("Server"); server != "" {
+		fp.Banner = server
+		// nginx/1.24.0、Apache/2.4.57 (Debian)
+		product := strings.Fields(server)[0]
+		fp.Product, fp.Version, _ = strings.Cut(product, "/")
+	}
+	return fp
+}
+
+// Redis PING，无需认证时再通过 INFO server 获取版本
+func probeRedis(conn net.Conn) *Fingerprint {
+	if _, err := conn.Write([]byte("PING\r\n")); err != nil {
+		return nil
+	}
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil
+	}
+	line = strings.TrimRight(line, "\r\n")
+
+	fp := &Fingerprint{Protocol: "redis", Product: "Redis", Banner: line}
+	switch {
+	case line == "+PONG":
+	case strings.HasPrefix(line, "-NOAUTH"), strings.HasPrefix(line, "-DENIED"), strings.HasPrefix(line, "-ERR"):
+		// 需要认证或处于保护模式，无法获取版本
+		return fp
+	default:
+		return nil
+	}
+
+	if _, err := conn.Write([]byte("INFO server\r\n")); err != nil {
+		return fp
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			break
+		}
+		if version, ok := strings.CutPrefix(strings.TrimSpace(line), "redis_version:"); ok {
+			fp.Version = version
+			break
+		}
+	}
+	return fp
+}
+
+// Lookup 返回服务的识别结果
+func (f *Fingerprinter) Lookup(id string) (Fingerprint, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	result, ok := f.results[id]
+	if !ok {
+		return Fingerprint{}, false
+	}
+	return *result, true
+}
+
+// Annotate 把识别结果标注到服务上，没有识别结果时只使用端口注册表
+func (f *Fingerprinter) Annotate(services []Service) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for i := range services {
+		service := &services[i]
+		fp := &Fingerprint{Source: fingerprintPort}
+		if service.Netns == "" || service.Netns == "host" {
+			if result, ok := f.results[serviceID(*service)]; ok {
+				copied := *result
+				fp = &copied
+			}
+		}
+		if entry, ok := f.registry.Lookup(service.LocalPort, service.Protocol); ok {
+			fp.Service = entry.Name
+		}
+		if fp.Service != "" || fp.Protocol != "" {
+			service.Fingerprint = fp
+		}
+	}
+}
+
+// 识别结果
+func fingerprintsHandler(f *Fingerprinter, sampler *Sampler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		snapshot, err := currentSnapshot(sampler)
+		if err != nil {
+			log.Printf("获取服务信息失败: %v\n", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		services := append([]Service(nil), snapshot.Services...)
+		f.Annotate(services)
+		sortServices(services)
+
+		type result struct {
+			ServiceID   string       `json:"service_id"`
+			Netns       string       `json:"netns,omitempty"`
+			Process     string       `json:"process"`
+			Fingerprint *Fingerprint `json:"fingerprint"`
+		}
+		results := []result{}
+		for _, service := range services {
+			if service.Fingerprint != nil {
+				results = append(results, result{serviceID(service), service.Netns, service.Name, service.Fingerprint})
+			}
+		}
+		sort.SliceStable(results, func(i, j int) bool {
+			// 识别到协议的在前
+			return results[i].Fingerprint.Protocol != "" && results[j].Fingerprint.Protocol == ""
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results)
+	}
+}
@@ -0,0 +1,116 @@
+package backend
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGreeting(t *testing.T) {
+	mysql := func(version string) []byte {
+		payload := append([]byte{0x0a}, version...)
+		payload = append(payload, 0, 1, 2, 3)
+		return append([]byte{byte(len(payload)), 0, 0, 0}, payload...)
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		protocol string
+		product  string
+		version  string
+	}{
+		{"SSH", []byte("SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13\r\n"), "ssh", "OpenSSH", "9.6p1"},
+		{"MySQL", mysql("8.0.36"), "mysql", "MySQL", "8.0.36"},
+		{"MariaDB", mysql("5.5.5-10.11.6-MariaDB-0+deb12u1"), "mysql", "MariaDB", "10.11.6"},
+		{"SMTP", []byte("220 mail.example.com ESMTP Postfix\r\n"), "smtp", "", ""},
+		{"FTP", []byte("220 (vsFTPd 3.0.5)\r\n"), "ftp", "", ""},
+		{"未知", []byte("hello\r\n"), "", "", ""},
+	}
+	for _, tt := range tests {
+		fp := parseGreeting(tt.data)
+		if fp.Protocol != tt.protocol || fp.Product != tt.product || fp.Version != tt.version {
+			t.Errorf("%s: %s %s %s, want %s %s %s", tt.name, fp.Protocol, fp.Product, fp.Version, tt.protocol, tt.product, tt.version)
+		}
+	}
+}
+
+func TestFingerprintSanitize(t *testing.T) {
+	fp := parseGreeting([]byte("SSH-2.0-Evil\x1b[31m_1.0\x00\x07<img src=x onerror=alert(1)>\r\n"))
+	fp.sanitize()
+	for _, value := range []string{fp.Product, fp.Version, fp.Banner} {
+		if strings.ContainsAny(value, "\x00\x07\x1b") {
+			t.Errorf("没有去掉控制字符: %q", value)
+		}
+	}
+	if fp.Product != "Evil[31m" {
+		t.Errorf("product = %q", fp.Product)
+	}
+
+	long := &Fingerprint{Version: strings.Repeat("9", 1000)}
+	long.sanitize()
+	if len(long.Version) != fingerprintMaxBanner {
+		t.Errorf("version 长度 = %d, want %d", len(long.Version), fingerprintMaxBanner)
+	}
+}
+
+func TestProbeRedisSanitizesVersion(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 1024)
+		conn.Read(buf)
+		conn.Write([]byte("+PONG\r\n"))
+		conn.Read(buf)
+		conn.Write([]byte("$40\r\n# Server\r\nredis_version:7.2.4\x1b]0;pwned\x07\r\n"))
+	}()
+
+	fp := runProbe(listener.Addr().String(), fingerprintProbe{"redis", probeRedis}, false)
+	if fp == nil {
+		t.Fatal("没有识别到Redis")
+	}
+	if fp.Product != "Redis" || fp.Version != "7.2.4]0;pwned" {
+		t.Errorf("product=%q version=%q", fp.Product, fp.Version)
+	}
+}
+
+func TestFingerprinterReady(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+
+	sampler := NewSampler(0, nil)
+	sampler.latest = &Snapshot{Time: time.Now(), Services: []Service{{Protocol: "tcp", LocalAddr: "127.0.0.1", LocalPort: port}}}
+	fingerprinter := NewFingerprinter(&PortRegistry{entries: make(map[string]PortEntry)}, sampler)
+
+	select {
+	case <-fingerprinter.Ready():
+		t.Fatal("启动前不应就绪")
+	default:
+	}
+
+	fingerprinter.Start()
+	select {
+	case <-fingerprinter.Ready():
+	case <-time.After(10 * fingerprintTimeout):
+		t.Fatal("第一轮识别没有完成")
+	}
+
+	// 就绪时第一轮的识别结果已经可以查询，HTTP端点发现依赖这一点
+	fp, ok := fingerprinter.Lookup("127.0.0.1:" + port + ":tcp")
+	if !ok || fp.Protocol != "http" {
+		t.Errorf("Lookup() = %+v, %v, want http", fp, ok)
+	}
+}
@@ -0,0 +1,563 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认采样间隔（秒）和历史保留天数
+const (
+	defaultHistoryInterval  = 60
+	defaultHistoryRetention = 7
+)
+
+// 历史分段文件按天切分，文件名为日期
+const (
+	historySegmentLayout = "2006-01-02"
+	historySegmentExt    = ".jsonl"
+)
+
+// 记录最后一个快照ID的文件，保留策略删除了全部分段后序号也不会重新开始
+const historySequenceFile = "sequence"
+
+// 历史中不存在满足条件的快照
+var errSnapshotNotFound = errors.New("没有找到对应的历史快照")
+
+// 某一时刻的服务列表
+type Snapshot struct {
+	ID       int64     `json:"id"`
+	Time     time.Time `json:"time"`
+	Services []Service `json:"services"`
+}
+
+// 快照列表中的摘要信息
+type SnapshotInfo struct {
+	ID       int64     `json:"id"`
+	Time     time.Time `json:"time"`
+	Services int       `json:"services"`
+}
+
+// 服务在历史中连续存在的一段时间，Vanished为空表示目前仍然存在
+type ServicePresence struct {
+	Appeared time.Time  `json:"appeared"`
+	Vanished *time.Time `json:"vanished"`
+}
+
+// 与前端一致的服务标识：地址:端口:协议，其他网络命名空间中的服务加上"命名空间|"前缀，
+// 主机上的服务标识保持不变，已保存的服务名称和URL路径仍然有效
+func serviceID(service Service) string {
+	id := service.LocalAddr + ":" + service.LocalPort + ":" + service.Protocol
+	if service.Netns != "" && service.Netns != "host" {
+		id = service.Netns + "|" + id
+	}
+	return id
+}
+
+// 判断服务列表是否变化时使用的特征，不包含队列长度等频繁变化的字段
+func snapshotKey(services []Service) string {
+	keys := make([]string, 0, len(services))
+	for _, service := range services {
+		keys = append(keys, strings.Join([]string{serviceID(service), service.Netns, service.State, service.Name, service.PID}, "|"))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\n")
+}
+
+// History 以追加写的分段文件保存服务列表快照。只有服务列表发生变化时才写入新快照，
+// 因此某一时刻的服务列表就是该时刻之前最近的一个快照
+type History struct {
+	mu        sync.Mutex
+	dir       string
+	retention time.Duration
+	lastID    int64
+	lastKey   string
+	lastPrune time.Time
+}
+
+// NewHistory 打开历史目录，并从最后一个快照恢复序号
+func NewHistory(dir string, retentionDays int) (*History, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	h := &History{
+		dir:       dir,
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+	}
+	h.prune(time.Now())
+
+	var last *Snapshot
+	err := h.each(func(snapshot *Snapshot) bool {
+		last = snapshot
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		h.lastID = last.ID
+		h.lastKey = snapshotKey(last.Services)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, historySequenceFile)); err == nil {
+		if id, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64); err == nil && id > h.lastID {
+			h.lastID = id
+		}
+	}
+	return h, nil
+}
+
+// 分段文件按日期排序的路径列表
+func (h *History) segments() ([]string, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), historySegmentExt) {
+			continue
+		}
+		segments = append(segments, filepath.Join(h.dir, entry.Name()))
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+// 按时间顺序遍历所有快照，fn返回false时停止
+func (h *History) each(fn func(*Snapshot) bool) error {
+	segments, err := h.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		more, err := readSegment(segment, fn)
+		if err != nil {
+			return err
+		}
+		if !more {
+			return nil
+		}
+	}
+	return nil
+}
+
+func readSegment(path string, fn func(*Snapshot) bool) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// 遍历期间被保留策略删除
+			return true, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if len(data) > 0 {
+			var snapshot Snapshot
+			if jsonErr := json.Unmarshal(data, &snapshot); jsonErr != nil {
+				// 写入时崩溃会留下不完整的最后一行，跳过即可
+				log.Printf("跳过历史文件 %s 第 %d 行: %v\n", filepath.Base(path), line, jsonErr)
+			} else if !fn(&snapshot) {
+				return false, nil
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	return true, nil
+}
+
+// Append 在服务列表相对上一个快照发生变化时写入快照，并返回是否写入。
+// 未变化时快照沿用上一个快照的ID
+func (h *History) Append(snapshot *Snapshot) (bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := snapshotKey(snapshot.Services)
+	if h.lastID != 0 && key == h.lastKey {
+		snapshot.ID = h.lastID
+		return false, nil
+	}
+
+	snapshot.ID = h.lastID + 1
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return false, err
+	}
+	data = append(data, '\n')
+
+	segment := filepath.Join(h.dir, snapshot.Time.Format(historySegmentLayout)+historySegmentExt)
+	file, err := os.OpenFile(segment, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return false, err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return false, err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return false, err
+	}
+	if err := file.Close(); err != nil {
+		return false, err
+	}
+
+	h.lastID = snapshot.ID
+	h.lastKey = key
+	if err := writeFileAtomic(filepath.Join(h.dir, historySequenceFile), []byte(strconv.FormatInt(h.lastID, 10)+"\n"), 0644); err != nil {
+		log.Printf("保存历史快照序号失败: %v\n", err)
+	}
+
+	if time.Since(h.lastPrune) > time.Hour {
+		h.prune(snapshot.Time)
+	}
+	return true, nil
+}
+
+// 删除超过保留期限的分段文件。保留天数小于等于0时不删除。
+// 期限之前最近的一个快照描述了期限开始时的服务列表，需要保留：
+// 最新的过期分段只截断为其中最后一个快照，不删除
+func (h *History) prune(now time.Time) {
+	h.lastPrune = now
+	if h.retention <= 0 {
+		return
+	}
+
+	segments, err := h.segments()
+	if err != nil {
+		log.Printf("读取历史目录失败: %v\n", err)
+		return
+	}
+
+	cutoff := now.Add(-h.retention).Format(historySegmentLayout)
+	var expired []string
+	for _, segment := range segments {
+		day := strings.TrimSuffix(filepath.Base(segment), historySegmentExt)
+		if day < cutoff {
+			expired = append(expired, segment)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	for _, segment := range expired[:len(expired)-1] {
+		if err := os.Remove(segment); err != nil {
+			log.Printf("删除过期历史文件失败: %v\n", err)
+		} else {
+			log.Printf("删除过期历史文件: %s\n", filepath.Base(segment))
+		}
+	}
+	h.truncateSegment(expired[len(expired)-1])
+}
+
+// 把分段文件截断为其中最后一个快照
+func (h *History) truncateSegment(segment string) {
+	var last *Snapshot
+	count := 0
+	if _, err := readSegment(segment, func(snapshot *Snapshot) bool {
+		last = snapshot
+		count++
+		return true
+	}); err != nil {
+		log.Printf("读取历史文件失败: %v\n", err)
+		return
+	}
+	if count <= 1 {
+		return
+	}
+
+	data, err := json.Marshal(last)
+	if err != nil {
+		log.Printf("序列化历史快照失败: %v\n", err)
+		return
+	}
+	if err := writeFileAtomic(segment, append(data, '\n'), 0644); err != nil {
+		log.Printf("截断过期历史文件失败: %v\n", err)
+		return
+	}
+	log.Printf("过期历史文件 %s 只保留最后一个快照 #%d\n", filepath.Base(segment), last.ID)
+}
+
+// List 返回所有快照的摘要
+func (h *History) List() ([]SnapshotInfo, error) {
+	infos := []SnapshotInfo{}
+	err := h.each(func(snapshot *Snapshot) bool {
+		infos = append(infos, SnapshotInfo{ID: snapshot.ID, Time: snapshot.Time, Services: len(snapshot.Services)})
+		return true
+	})
+	return infos, err
+}
+
+// At 返回指定时刻的服务列表，即该时刻之前最近的快照
+func (h *History) At(t time.Time) (*Snapshot, error) {
+	var found *Snapshot
+	err := h.each(func(snapshot *Snapshot) bool {
+		if snapshot.Time.After(t) {
+			return false
+		}
+		found = snapshot
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, errSnapshotNotFound
+	}
+	return found, nil
+}
+
+// Get 按ID返回快照
+func (h *History) Get(id int64) (*Snapshot, error) {
+	var found *Snapshot
+	err := h.each(func(snapshot *Snapshot) bool {
+		if snapshot.ID == id {
+			found = snapshot
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, errSnapshotNotFound
+	}
+	return found, nil
+}
+
+// Timeline 返回服务每次出现和消失的时间
+func (h *History) Timeline(id string) ([]ServicePresence, error) {
+	timeline := []ServicePresence{}
+	present := false
+	err := h.each(func(snapshot *Snapshot) bool {
+		found := false
+		for _, service := range snapshot.Services {
+			if serviceID(service) == id {
+				found = true
+				break
+			}
+		}
+
+		switch {
+		case found && !present:
+			timeline = append(timeline, ServicePresence{Appeared: snapshot.Time})
+		case !found && present:
+			vanished := snapshot.Time
+			timeline[len(timeline)-1].Vanished = &vanished
+		}
+		present = found
+		return true
+	})
+	return timeline, err
+}
+
+// Sampler 定期采集服务列表写入历史，并把每次采集的结果推送给订阅者
+type Sampler struct {
+	interval time.Duration
+	history  *History
+
+	mu          sync.RWMutex
+	latest      *Snapshot
+	subscribers map[chan *Snapshot]struct{}
+}
+
+// NewSampler 创建采样器，interval小于等于0时只在有订阅者时按默认间隔采样
+func NewSampler(interval time.Duration, history *History) *Sampler {
+	return &Sampler{
+		interval:    interval,
+		history:     history,
+		subscribers: make(map[chan *Snapshot]struct{}),
+	}
+}
+
+// 实际的采样间隔。历史和实时推送都关闭时，基线、事件和规则仍然依赖采样结果，
+// 有订阅者时按历史的默认间隔采样，返回0表示不采样
+func (s *Sampler) effectiveInterval() time.Duration {
+	if s.interval > 0 {
+		return s.interval
+	}
+
+	s.mu.RLock()
+	subscribers := len(s.subscribers)
+	s.mu.RUnlock()
+	if subscribers == 0 {
+		return 0
+	}
+	return defaultHistoryInterval * time.Second
+}
+
+// Start 在后台开始采样，应在全部订阅者订阅之后调用
+func (s *Sampler) Start() {
+	interval := s.effectiveInterval()
+	if interval <= 0 {
+		log.Println("后台采样已禁用")
+		return
+	}
+	if interval != s.interval {
+		log.Printf("历史和实时推送都已关闭，基线、事件和规则仍按 %s 的间隔采样\n", interval)
+	}
+
+	go func() {
+		s.sample()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.sample()
+		}
+	}()
+}
+
+func (s *Sampler) sample() {
+	services, err := getServices()
+	if err != nil {
+		log.Printf("采样服务列表失败: %v\n", err)
+		return
+	}
+
+	snapshot := &Snapshot{Time: time.Now(), Services: services}
+	if s.history != nil {
+		written, err := s.history.Append(snapshot)
+		if err != nil {
+			log.Printf("写入历史快照失败: %v\n", err)
+		} else if written {
+			log.Printf("服务列表发生变化，写入历史快照 #%d（%d 个服务）\n", snapshot.ID, len(services))
+		}
+	}
+
+	s.publish(snapshot)
+}
+
+// 保存最近一次采样的结果并推送给订阅者
+func (s *Sampler) publish(snapshot *Snapshot) {
+	s.mu.Lock()
+	s.latest = snapshot
+	for ch := range s.subscribers {
+		// 订阅者处理不过来时丢弃旧的快照，只保留最新的
+		select {
+		case ch <- snapshot:
+		default:
+			select {
+			case <-ch:
+			default:
+			}
+			select {
+			case ch <- snapshot:
+			default:
+			}
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Latest 返回最近一次采样的结果，尚未采样时返回nil
+func (s *Sampler) Latest() *Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.latest
+}
+
+// Subscribe 订阅每次采样的结果，返回的函数用于取消订阅
+func (s *Sampler) Subscribe() (<-chan *Snapshot, func()) {
+	ch := make(chan *Snapshot, 1)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+}
+
+// 解析时间参数：支持RFC3339和Unix时间戳（秒）
+func parseHistoryTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的时间 %q，应为RFC3339格式或Unix时间戳", value)
+	}
+	return t, nil
+}
+
+// 历史快照：不带参数时返回快照列表，?at= 返回该时刻的服务列表
+func historyHandler(history *History) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到查询历史快照的请求")
+		w.Header().Set("Content-Type", "application/json")
+
+		at := r.URL.Query().Get("at")
+		if at == "" {
+			infos, err := history.List()
+			if err != nil {
+				log.Printf("读取历史快照失败: %v\n", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(infos)
+			log.Printf("成功返回 %d 个历史快照\n", len(infos))
+			return
+		}
+
+		t, err := parseHistoryTime(at)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		snapshot, err := history.At(t)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errSnapshotNotFound) {
+				status = http.StatusNotFound
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		json.NewEncoder(w).Encode(snapshot)
+		log.Printf("成功返回历史快照 #%d\n", snapshot.ID)
+	}
+}
+
+// 服务的出现和消失时间线，?service_id=地址:端口:协议
+func historyTimelineHandler(history *History) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("service_id")
+		log.Printf("接收到查询服务时间线的请求: %s\n", id)
+		if id == "" {
+			http.Error(w, "缺少service_id参数", http.StatusBadRequest)
+			return
+		}
+
+		timeline, err := history.Timeline(id)
+		if err != nil {
+			log.Printf("读取历史快照失败: %v\n", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(timeline)
+	}
+}
@@ -0,0 +1,142 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func historySnapshot(t time.Time, ports ...string) *Snapshot {
+	snapshot := &Snapshot{Time: t}
+	for _, port := range ports {
+		snapshot.Services = append(snapshot.Services, Service{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: port})
+	}
+	return snapshot
+}
+
+func appendSnapshot(t *testing.T, h *History, snapshot *Snapshot) {
+	t.Helper()
+	if _, err := h.Append(snapshot); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryPruneKeepsLastExpiredSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	h, err := NewHistory(dir, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	appendSnapshot(t, h, historySnapshot(start, "22"))
+	appendSnapshot(t, h, historySnapshot(start.Add(time.Hour), "22", "80"))
+	appendSnapshot(t, h, historySnapshot(start.Add(24*time.Hour), "22", "80", "443"))
+
+	// 之后30天服务列表没有变化，全部分段都已过期
+	now := start.Add(30 * 24 * time.Hour)
+	h.prune(now)
+
+	segments, err := h.segments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segments) != 1 || filepath.Base(segments[0]) != "2026-01-02.jsonl" {
+		t.Fatalf("segments = %v, want 只保留 2026-01-02.jsonl", segments)
+	}
+
+	snapshot, err := h.At(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("保留期内的时刻应该能查到快照: %v", err)
+	}
+	if snapshot.ID != 3 || len(snapshot.Services) != 3 {
+		t.Errorf("At() = #%d（%d 个服务），want #3（3 个服务）", snapshot.ID, len(snapshot.Services))
+	}
+}
+
+func TestHistoryPruneTruncatesLastExpiredSegment(t *testing.T) {
+	dir := t.TempDir()
+	h, err := NewHistory(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	appendSnapshot(t, h, historySnapshot(start, "22"))
+	appendSnapshot(t, h, historySnapshot(start.Add(time.Hour), "22", "80"))
+	appendSnapshot(t, h, historySnapshot(start.Add(2*time.Hour), "80"))
+
+	h.prune(start.Add(5 * 24 * time.Hour))
+
+	infos, err := h.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].ID != 3 {
+		t.Errorf("List() = %+v, want 只剩快照 #3", infos)
+	}
+}
+
+func TestHistoryIDSurvivesPrune(t *testing.T) {
+	dir := t.TempDir()
+	h, err := NewHistory(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	appendSnapshot(t, h, historySnapshot(start, "22"))
+	appendSnapshot(t, h, historySnapshot(start.Add(time.Hour), "22", "80"))
+
+	// 手工删除了全部分段
+	segments, _ := h.segments()
+	for _, segment := range segments {
+		os.Remove(segment)
+	}
+
+	reopened, err := NewHistory(dir, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := historySnapshot(start.Add(48*time.Hour), "443")
+	appendSnapshot(t, reopened, snapshot)
+	if snapshot.ID != 3 {
+		t.Errorf("新快照ID = %d, want 3（序号不能重新开始）", snapshot.ID)
+	}
+}
+
+func TestHistoryAppendSkipsUnchanged(t *testing.T) {
+	h, err := NewHistory(t.TempDir(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	appendSnapshot(t, h, historySnapshot(now, "22"))
+	second := historySnapshot(now.Add(time.Minute), "22")
+	written, err := h.Append(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written || second.ID != 1 {
+		t.Errorf("未变化的服务列表: written=%v id=%d, want false/1", written, second.ID)
+	}
+}
+
+func TestServiceIDNetns(t *testing.T) {
+	tests := []struct {
+		netns string
+		want  string
+	}{
+		{"", "0.0.0.0:80:tcp"},
+		{"host", "0.0.0.0:80:tcp"},
+		{"app", "app|0.0.0.0:80:tcp"},
+	}
+	for _, tt := range tests {
+		service := Service{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "80", Netns: tt.netns}
+		if got := serviceID(service); got != tt.want {
+			t.Errorf("serviceID(netns=%q) = %q, want %q", tt.netns, got, tt.want)
+		}
+	}
+}
@@ -14,19 +14,50 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	// 添加yaml支持
 	"gopkg.in/yaml.v2"
 )
 
 type Service struct {
-	Name        string `json:"name"`
-	Protocol    string `json:"protocol"`
-	LocalAddr   string `json:"local_addr"`
-	LocalPort   string `json:"local_port"`
-	ForeignAddr string `json:"foreign_addr"`
-	State       string `json:"state"`
-	PID         string `json:"pid"`
+	Name        string       `json:"name"`
+	Protocol    string       `json:"protocol"`
+	LocalAddr   string       `json:"local_addr"`
+	LocalPort   string       `json:"local_port"`
+	ForeignAddr string       `json:"foreign_addr"`
+	State       string       `json:"state"`
+	PID         string       `json:"pid"`
+	Process     *ProcessInfo `json:"process,omitempty"`
+	Unit        string       `json:"unit,omitempty"`   // systemd单元
+	Slice       string       `json:"slice,omitempty"`  // systemd slice
+	Cgroup      string       `json:"cgroup,omitempty"` // 完整cgroup路径
+
+	ContainerID      string `json:"container_id,omitempty"`
+	ContainerRuntime string `json:"container_runtime,omitempty"`
+	ContainerName    string `json:"container_name,omitempty"`
+	ContainerImage   string `json:"container_image,omitempty"`
+
+	Netns      string `json:"netns,omitempty"` // 网络命名空间
+	NetnsInode uint64 `json:"netns_inode,omitempty"`
+
+	Connections *ConnectionStats `json:"connections,omitempty"` // 连接模式下的客户端统计
+
+	// 对监听套接字，RecvQ为当前accept队列长度，SendQ为backlog上限
+	RecvQ          int  `json:"recv_q"`
+	SendQ          int  `json:"send_q"`
+	BacklogUnknown bool `json:"backlog_unknown,omitempty"` // 采集方式无法获取backlog，此时SendQ为0
+	Saturated      bool `json:"saturated,omitempty"`       // accept队列接近backlog上限
+
+	// 相对端口基线的分类：expected / unexpected，未配置基线时为空
+	Baseline string `json:"baseline,omitempty"`
+
+	Exposure *Exposure    `json:"exposure,omitempty"` // 暴露范围和风险等级
+	Health   *ProbeResult `json:"health,omitempty"`   // 最近一次健康探测结果
+	TLS      *TLSInfo     `json:"tls,omitempty"`      // TLS证书信息
+
+	Fingerprint *Fingerprint   `json:"fingerprint,omitempty"` // 识别到的协议和版本
+	Endpoints   []HTTPEndpoint `json:"endpoints,omitempty"`   // 通过各网卡地址访问HTTP服务的结果
 }
 
 type InterfaceInfo struct {
@@ -37,6 +68,12 @@ type InterfaceInfo struct {
 type Config struct {
 	WebPort           int
 	ExcludeInterfaces []string
+	Collector         string
+	DockerSocket      string
+	ScanNamespaces    bool
+	StateDir          string
+	HistoryInterval   int // 秒
+	HistoryRetention  int // 天
 }
 
 type InterfaceConfig struct {
@@ -47,11 +84,24 @@ type InterfaceConfig struct {
 // 添加配置结构体
 type YAMLConfig struct {
 	ServiceConfig []struct {
-		Addr     string `yaml:"addr"`
-		Port     int    `yaml:"port"`
-		Exclude  string `yaml:"exclude"`
-		GetIpUrl string `yaml:"get_ip_url"` // 添加GetIpUrl字段
+		Addr             string `yaml:"addr"`
+		Port             int    `yaml:"port"`
+		Exclude          string `yaml:"exclude"`
+		GetIpUrl         string `yaml:"get_ip_url"`        // 添加GetIpUrl字段
+		Collector        string `yaml:"collector"`         // 套接字采集后端
+		DockerSocket     string `yaml:"docker_socket"`     // Docker Engine API套接字
+		ScanNamespaces   bool   `yaml:"scan_namespaces"`   // 扫描所有网络命名空间
+		StateDir         string `yaml:"state_dir"`         // 数据文件、日志等状态文件的目录
+		HistoryInterval  int    `yaml:"history_interval"`  // 历史采样间隔（秒）
+		HistoryRetention int    `yaml:"history_retention"` // 历史保留天数
 	} `yaml:"service-config"`
+	Alerts AlertConfig  `yaml:"alerts"` // 监听变化的告警规则和通知方式
+	Rules  []RuleConfig `yaml:"rules"`  // 服务和网卡的条件规则，通过alerts中的通知方式发送
+	Probes ProbeConfig  `yaml:"probes"` // 健康探测
+	TLS    TLSConfig    `yaml:"tls"`    // TLS证书检查
+
+	Discovery DiscoveryConfig `yaml:"http_discovery"` // HTTP端点发现
+	Stream    StreamConfig    `yaml:"stream"`         // 实时推送
 }
 
 // 添加列配置结构体
@@ -75,21 +125,6 @@ type URLPathMapping struct {
 
 var config Config
 
-// 添加全局变量存储服务名称映射
-var serviceNames = make(map[string]string)
-
-// 添加全局变量存储URL路径映射
-var urlPaths = make(map[string]string)
-
-// 添加全局变量存储接口配置
-var interfaceConfigs = make(map[string]bool)
-
-// 添加全局变量存储列配置
-var columnConfigs = make(map[string]map[string]bool)
-
-// 使用相对路径而不是绝对路径
-var dataFile = "data.json"
-
 // 添加日志文件
 var logFile *os.File
 
@@ -147,26 +182,55 @@ func getIndexFilePath() string {
 	return indexFilePath
 }
 
+// 命令行中是否显式指定了参数
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
+
 // StartServer 启动Web服务器
 func StartServer() {
 	// 解析命令行参数
 	webPort := flag.Int("webport", 10810, "Web界面监听端口")
 	exclude := flag.String("exclude", "lo,br-,veth,docker0", "排除的网卡前缀，逗号分隔")
+	collector := flag.String("collector", collectorAuto, "套接字采集后端: auto, netlink, procfs, ss")
+	dockerSocket := flag.String("docker-socket", defaultDockerSocket, "Docker Engine API套接字路径，留空则不查询容器")
+	scanNamespaces := flag.Bool("scan-namespaces", false, "扫描所有网络命名空间中的端口")
+	stateDir := flag.String("statedir", "", "状态目录（数据文件、日志），留空则自动选择")
+	historyInterval := flag.Int("history-interval", defaultHistoryInterval, "历史采样间隔（秒），小于0表示不保存历史")
+	historyRetention := flag.Int("history-retention", defaultHistoryRetention, "历史保留天数，小于0表示永久保留")
 	flag.Parse()
 
 	// 读取YAML配置文件
 	yamlConfig := &YAMLConfig{
 		ServiceConfig: []struct {
-			Addr     string `yaml:"addr"`
-			Port     int    `yaml:"port"`
-			Exclude  string `yaml:"exclude"`
-			GetIpUrl string `yaml:"get_ip_url"`
+			Addr             string `yaml:"addr"`
+			Port             int    `yaml:"port"`
+			Exclude          string `yaml:"exclude"`
+			GetIpUrl         string `yaml:"get_ip_url"`
+			Collector        string `yaml:"collector"`
+			DockerSocket     string `yaml:"docker_socket"`
+			ScanNamespaces   bool   `yaml:"scan_namespaces"`
+			StateDir         string `yaml:"state_dir"`
+			HistoryInterval  int    `yaml:"history_interval"`
+			HistoryRetention int    `yaml:"history_retention"`
 		}{
 			{
-				Addr:     "0.0.0.0", // 设置默认监听地址
-				Port:     *webPort,
-				Exclude:  *exclude,
-				GetIpUrl: "https://4.ipw.cn", // 设置默认公网IP服务地址
+				Addr:             "0.0.0.0", // 设置默认监听地址
+				Port:             *webPort,
+				Exclude:          *exclude,
+				GetIpUrl:         "https://4.ipw.cn", // 设置默认公网IP服务地址
+				Collector:        *collector,
+				DockerSocket:     *dockerSocket,
+				ScanNamespaces:   *scanNamespaces,
+				StateDir:         *stateDir,
+				HistoryInterval:  *historyInterval,
+				HistoryRetention: *historyRetention,
 			},
 		},
 	}
@@ -184,6 +248,12 @@ func StartServer() {
     port: 10810           # 监听端口
     exclude: "lo,br-,veth,docker0" # 忽略网卡
     get_ip_url: "https://4.ipw.cn"  # 公网IP服务地址
+    collector: "auto"     # 套接字采集后端: auto, netlink, procfs, ss
+    docker_socket: "/var/run/docker.sock" # Docker套接字，用于识别容器，none表示不查询
+    scan_namespaces: false # 是否扫描容器等其他网络命名空间
+    history_interval: 60  # 历史采样间隔（秒），-1表示不保存历史
+    history_retention: 7  # 历史保留天数，-1表示永久保留
+    state_dir: ""         # 状态目录，留空则root使用/var/lib/port-monitor，其他用户使用~/.local/state/port-monitor
 `
 
 		// 写入文件
@@ -213,9 +283,49 @@ func StartServer() {
 	mainConfig := yamlConfig.ServiceConfig[0]
 	config.WebPort = mainConfig.Port
 	config.ExcludeInterfaces = strings.Split(mainConfig.Exclude, ",")
+	config.Collector = mainConfig.Collector
+	if config.Collector == "" {
+		config.Collector = *collector
+	}
+	log.Printf("套接字采集后端: %s\n", config.Collector)
+	// 配置文件中未设置时使用命令行参数，设置为none表示不查询容器
+	config.DockerSocket = mainConfig.DockerSocket
+	if config.DockerSocket == "" {
+		config.DockerSocket = *dockerSocket
+	}
+	if config.DockerSocket == dockerSocketDisabled {
+		config.DockerSocket = ""
+	}
+	// 默认配置文件总是包含scan_namespaces，命令行显式指定时优先于配置文件
+	config.ScanNamespaces = mainConfig.ScanNamespaces
+	if flagPassed("scan-namespaces") {
+		config.ScanNamespaces = *scanNamespaces
+	}
+	// 配置文件中未设置（为0）时使用命令行参数
+	config.HistoryInterval = mainConfig.HistoryInterval
+	if config.HistoryInterval == 0 {
+		config.HistoryInterval = *historyInterval
+	}
+	config.HistoryRetention = mainConfig.HistoryRetention
+	if config.HistoryRetention == 0 {
+		config.HistoryRetention = *historyRetention
+	}
+
+	// 命令行参数优先于配置文件
+	config.StateDir = *stateDir
+	if config.StateDir == "" {
+		config.StateDir = mainConfig.StateDir
+	}
+	if config.StateDir == "" {
+		config.StateDir = defaultStateDir()
+	}
+	if err := initStateDir(config.StateDir); err != nil {
+		fmt.Printf("无法创建状态目录 %s: %v\n", config.StateDir, err)
+		os.Exit(1)
+	}
 
 	// 初始化日志文件
-	logFile, err := os.OpenFile("server.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	logFile, err := os.OpenFile(statePath(logFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
 		fmt.Printf("无法打开日志文件: %v\n", err)
 		os.Exit(1)
@@ -226,21 +336,108 @@ func StartServer() {
 	log.SetOutput(io.MultiWriter(os.Stdout, logFile))
 
 	// 加载已保存的服务名称
-	loadServiceNames()
+	log.Printf("状态目录: %s\n", config.StateDir)
+	store := NewStore(statePath(dataFileName))
+	store.Load()
+
+	// 历史快照和后台采样
+	history, err := NewHistory(statePath(historyDirName), config.HistoryRetention)
+	if err != nil {
+		log.Fatalf("无法打开历史目录: %v\n", err)
+	}
+	// 历史、基线、告警和实时推送共用一个采样器，history_interval小于0时不写入历史
+	samplerHistory := history
+	if config.HistoryInterval < 0 {
+		samplerHistory = nil
+	}
+	sampler := NewSampler(samplingInterval(time.Duration(config.HistoryInterval)*time.Second, yamlConfig.Stream), samplerHistory)
+
+	// 端口基线，与采样器的每次采样结果比较
+	baseline := NewBaseline(baselinePath())
+	baseline.Watch(sampler)
+	// 监听变化事件和告警通知
+	events := NewEventEngine(yamlConfig.Alerts)
+	events.Watch(sampler)
+	// 服务和网卡的条件规则
+	rules := NewRuleEngine(yamlConfig.Rules, baseline, events)
+	rules.Watch(sampler)
+	sampler.Start()
+
+	// 监听的暴露分析
+	exposure := NewExposureAnalyzer()
+	exposure.Start()
+
+	// 健康探测
+	prober := NewProber(yamlConfig.Probes, store, sampler)
+	prober.Start()
+
+	// TLS证书检查
+	tlsInspector := NewTLSInspector(yamlConfig.TLS, sampler)
+	tlsInspector.Start()
+
+	// 端口注册表和协议识别
+	registry := NewPortRegistry(portRegistryPath())
+	fingerprinter := NewFingerprinter(registry, sampler)
+	fingerprinter.Start()
+
+	// HTTP端点发现
+	discovery := NewHTTPDiscovery(yamlConfig.Discovery, store, sampler, fingerprinter)
+	discovery.Start()
+
+	// 服务列表的标注，/api/services 和 /api/stream 共用
+	annotator := &ServiceAnnotator{
+		baseline:      baseline,
+		exposure:      exposure,
+		prober:        prober,
+		inspector:     tlsInspector,
+		fingerprinter: fingerprinter,
+		discovery:     discovery,
+	}
+	// 每次采样后推送服务列表的变化
+	stream := NewStream(yamlConfig.Stream, annotator, sampler)
+	stream.Start()
 
 	// 设置API路由
-	http.HandleFunc("/api/services", servicesHandler)
-	http.HandleFunc("/api/interfaces", interfacesHandler)
+	http.HandleFunc("/api/services", servicesHandler(stream, annotator))
+	http.HandleFunc("/api/interfaces", interfacesHandler(exposure))
+	// 添加获取TCP连接的路由
+	http.HandleFunc("/api/connections", connectionsHandler)
+	// 添加获取Unix域套接字的路由
+	http.HandleFunc("/api/unix-sockets", unixSocketsHandler)
 	// 添加保存服务名称的路由
-	http.HandleFunc("/api/save-service-name", saveServiceNameHandler)
+	http.HandleFunc("/api/save-service-name", saveServiceNameHandler(store))
 	// 添加获取已保存服务名称的路由
-	http.HandleFunc("/api/saved-service-names", savedServiceNamesHandler)
+	http.HandleFunc("/api/saved-service-names", savedServiceNamesHandler(store))
 	// 添加保存列配置的路由
-	http.HandleFunc("/api/save-column-config", saveColumnConfigHandler)
+	http.HandleFunc("/api/save-column-config", saveColumnConfigHandler(store))
 	// 添加保存URL路径的路由
-	http.HandleFunc("/api/save-url-path", saveURLPathHandler)
+	http.HandleFunc("/api/save-url-path", saveURLPathHandler(store))
 	// 添加生成随机端口的API
 	http.HandleFunc("/api/generate-ports", handleGeneratePorts)
+	// 添加历史快照的API
+	http.HandleFunc("/api/history", historyHandler(history))
+	http.HandleFunc("/api/history/timeline", historyTimelineHandler(history))
+	http.HandleFunc("/api/history/diff", historyDiffHandler(history, sampler))
+	// 添加基线比较的API
+	http.HandleFunc("/api/baseline", baselineHandler(baseline, sampler))
+	// 添加监听变化事件的API
+	http.HandleFunc("/api/events", eventsHandler(events))
+	http.HandleFunc("/api/events/silence", eventSilenceHandler(events))
+	// 添加规则状态的API
+	http.HandleFunc("/api/rules", rulesHandler(rules))
+	// 添加暴露分析的API
+	http.HandleFunc("/api/exposure", exposureHandler(exposure, sampler))
+	// 添加健康探测结果的API
+	http.HandleFunc("/api/probes", probesHandler(prober))
+	// 添加TLS证书检查的API
+	http.HandleFunc("/api/tls", tlsHandler(tlsInspector))
+	// 添加协议识别和端口注册表的API
+	http.HandleFunc("/api/fingerprints", fingerprintsHandler(fingerprinter, sampler))
+	http.HandleFunc("/api/ports", portRegistryHandler(registry))
+	// 添加HTTP端点发现的API
+	http.HandleFunc("/api/endpoints", endpointsHandler(discovery))
+	// 添加实时推送的API
+	http.HandleFunc("/api/stream", streamHandler(stream))
 
 	// 移除静态文件处理器，由前端路由处理
 	// 前端构建后的文件将通过根路径处理器提供服务
@@ -275,410 +472,259 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
-// 加载已保存的服务名称
-func loadServiceNames() {
-	file, err := os.Open(dataFile)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			log.Printf("读取数据文件失败: %v\n", err)
-		} else {
-			log.Println("数据文件不存在，将创建新文件")
-		}
-		return
-	}
-	defer file.Close()
+// ServiceAnnotator 在服务列表上标注基线、暴露范围、健康探测等结果
+type ServiceAnnotator struct {
+	baseline      *Baseline
+	exposure      *ExposureAnalyzer
+	prober        *Prober
+	inspector     *TLSInspector
+	fingerprinter *Fingerprinter
+	discovery     *HTTPDiscovery
+}
 
-	decoder := json.NewDecoder(file)
-	var data map[string]interface{}
-	if err := decoder.Decode(&data); err != nil {
-		log.Printf("解析数据文件失败: %v\n", err)
-		return
-	}
+// Annotate 依次标注每个服务
+func (a *ServiceAnnotator) Annotate(services []Service) {
+	// 标注每个服务是否在基线内
+	a.baseline.Evaluate(services)
+	// 标注每个服务的暴露范围
+	a.exposure.Analyze(services)
+	// 标注最近一次健康探测结果
+	a.prober.Annotate(services)
+	// 标注TLS证书信息
+	a.inspector.Annotate(services)
+	// 标注识别到的协议和版本
+	a.fingerprinter.Annotate(services)
+	// 标注HTTP端点的访问结果
+	a.discovery.Annotate(services)
+}
 
-	// 加载服务名称映射
-	if mappings, ok := data["service_names"].([]interface{}); ok {
-		for _, item := range mappings {
-			if mapping, ok := item.(map[string]interface{}); ok {
-				if serviceID, ok := mapping["service_id"].(string); ok {
-					if name, ok := mapping["name"].(string); ok {
-						serviceNames[serviceID] = name
-					}
-				}
-			}
-		}
-		log.Printf("加载了 %d 个服务名称映射\n", len(serviceNames))
-	}
+func servicesHandler(stream *Stream, annotator *ServiceAnnotator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到获取服务列表的请求")
+		connections := r.URL.Query().Get("connections") == "1"
 
-	// 加载接口配置
-	if configs, ok := data["interface_configs"].([]interface{}); ok {
-		for _, item := range configs {
-			if config, ok := item.(map[string]interface{}); ok {
-				if name, ok := config["name"].(string); ok {
-					if showLinks, ok := config["show_links"].(bool); ok {
-						interfaceConfigs[name] = showLinks
-					}
-				}
-			}
+		// 优先使用后台最近一次采集的结果，连接模式需要与连接信息同时采集
+		var services []Service
+		if !connections {
+			services = stream.Services()
 		}
-		log.Printf("加载了 %d 个接口配置\n", len(interfaceConfigs))
-	}
-
-	// 加载列配置
-	if colConfigs, ok := data["column_configs"].([]interface{}); ok {
-		for _, item := range colConfigs {
-			if config, ok := item.(map[string]interface{}); ok {
-				table := ""
-				column := ""
-				visible := true
-
-				if t, ok := config["table"].(string); ok {
-					table = t
-				}
-				if c, ok := config["column"].(string); ok {
-					column = c
-				}
-				if v, ok := config["visible"].(bool); ok {
-					visible = v
-				}
-
-				if table != "" && column != "" {
-					if columnConfigs[table] == nil {
-						columnConfigs[table] = make(map[string]bool)
-					}
-					columnConfigs[table][column] = visible
-				}
+		if services == nil {
+			var err error
+			services, err = getServices()
+			if err != nil {
+				log.Printf("获取服务信息失败: %v\n", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
 			}
 		}
-		log.Printf("加载了 %d 个表格的列配置\n", len(colConfigs))
-	}
 
-	// 加载URL路径映射
-	if mappings, ok := data["url_paths"].([]interface{}); ok {
-		for _, item := range mappings {
-			if mapping, ok := item.(map[string]interface{}); ok {
-				if serviceID, ok := mapping["service_id"].(string); ok {
-					if path, ok := mapping["path"].(string); ok {
-						urlPaths[serviceID] = path
-					}
-				}
+		// 连接模式：统计每个监听端口的客户端连接
+		if connections {
+			conns, err := getConnections()
+			if err != nil {
+				log.Printf("获取连接信息失败: %v\n", err)
+			} else {
+				attachConnectionStats(services, conns)
 			}
 		}
-		log.Printf("加载了 %d 个URL路径映射\n", len(urlPaths))
-	}
 
-	log.Printf("总共加载了 %d 个服务名称和 %d 个接口配置\n", len(serviceNames), len(interfaceConfigs))
-}
+		annotator.Annotate(services)
 
-// 保存服务名称到文件
-func saveServiceNames() error {
-	file, err := os.Create(dataFile)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	// 准备服务名称数据
-	var nameMappings []ServiceNameMapping
-	for serviceID, name := range serviceNames {
-		nameMappings = append(nameMappings, ServiceNameMapping{
-			Service_id: serviceID,
-			Name:       name,
-		})
-	}
-
-	// 准备接口配置数据
-	var interfaceConfigsData []InterfaceConfig
-	for name, showLinks := range interfaceConfigs {
-		interfaceConfigsData = append(interfaceConfigsData, InterfaceConfig{
-			Name:      name,
-			ShowLinks: showLinks,
-		})
-	}
-
-	// 准备列配置数据
-	var columnConfigsData []ColumnConfig
-	for table, columns := range columnConfigs {
-		for column, visible := range columns {
-			columnConfigsData = append(columnConfigsData, ColumnConfig{
-				Table:   table,
-				Column:  column,
-				Visible: visible,
-			})
-		}
-	}
-
-	// 准备URL路径数据
-	var urlPathMappings []URLPathMapping
-	for serviceID, path := range urlPaths {
-		urlPathMappings = append(urlPathMappings, URLPathMapping{
-			Service_id: serviceID,
-			Path:       path,
-		})
-	}
-
-	// 组合所有数据
-	data := map[string]interface{}{
-		"service_names":     nameMappings,
-		"interface_configs": interfaceConfigsData,
-		"column_configs":    columnConfigsData, // 添加列配置数据
-		"url_paths":         urlPathMappings,   // 添加URL路径数据
-	}
-
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-
-	err = encoder.Encode(data)
-	if err != nil {
-		log.Printf("保存数据到文件失败: %v\n", err)
-		return err
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(services)
+		log.Printf("成功返回 %d 个服务\n", len(services))
 	}
-
-	log.Printf("成功保存数据到文件，服务名称: %d个, 接口配置: %d个\n", len(nameMappings), len(interfaceConfigsData))
-	return nil
 }
 
-func servicesHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("接收到获取服务列表的请求")
-	services, err := getServices()
-	if err != nil {
-		log.Printf("获取服务信息失败: %v\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(services)
-	log.Printf("成功返回 %d 个服务\n", len(services))
-}
+func interfacesHandler(exposure *ExposureAnalyzer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到获取网络接口列表的请求")
+		interfaces, err := getNetworkInterfaces(exposure)
+		if err != nil {
+			log.Printf("获取接口信息失败: %v\n", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-func interfacesHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("接收到获取网络接口列表的请求")
-	interfaces, err := getNetworkInterfaces()
-	if err != nil {
-		log.Printf("获取接口信息失败: %v\n", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(interfaces)
+		log.Printf("成功返回 %d 个网络接口\n", len(interfaces))
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(interfaces)
-	log.Printf("成功返回 %d 个网络接口\n", len(interfaces))
 }
 
 // 保存服务名称处理器
-func saveServiceNameHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("接收到保存配置的请求")
-	if r.Method != http.MethodPost {
-		log.Printf("请求方法错误: %s\n", r.Method)
-		http.Error(w, "只支持POST方法", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var data map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		log.Printf("解析JSON数据失败: %v\n", err)
-		http.Error(w, "无效的JSON数据", http.StatusBadRequest)
-		return
-	}
-
-	// 判断是保存服务名称还是接口配置
-	if configType, ok := data["type"].(string); ok && configType == "interface_config" {
-		log.Println("处理接口配置保存请求")
-		// 保存接口配置
-		if name, ok := data["interface_name"].(string); ok {
-			if showLinks, ok := data["show_links"].(bool); ok {
-				interfaceConfigs[name] = showLinks
-				log.Printf("更新接口配置: %s = %v\n", name, showLinks)
-
-				// 保存到文件
-				if err := saveServiceNames(); err != nil {
-					log.Printf("保存接口配置失败: %v\n", err)
-					http.Error(w, "保存失败", http.StatusInternalServerError)
-					return
-				}
-
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte("保存成功"))
-				log.Println("接口配置保存成功")
-				return
-			}
-		}
-		log.Println("无效的接口配置数据")
-		http.Error(w, "无效的接口配置数据", http.StatusBadRequest)
-		return
-	} else {
-		log.Println("处理服务名称保存请求")
-		// 保存服务名称（使用已解析的数据）
-		serviceID, ok1 := data["service_id"].(string)
-		name, ok2 := data["name"].(string)
-
-		if !ok1 || !ok2 {
-			log.Println("无效的服务名称数据")
-			http.Error(w, "无效的服务名称数据", http.StatusBadRequest)
+func saveServiceNameHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到保存配置的请求")
+		if r.Method != http.MethodPost {
+			log.Printf("请求方法错误: %s\n", r.Method)
+			http.Error(w, "只支持POST方法", http.StatusMethodNotAllowed)
 			return
 		}
 
-		// 更新内存中的映射
-		serviceNames[serviceID] = name
-		log.Printf("更新服务名称映射: %s = %s\n", serviceID, name)
-
-		// 保存到文件
-		if err := saveServiceNames(); err != nil {
-			log.Printf("保存服务名称失败: %v\n", err)
-			http.Error(w, "保存失败", http.StatusInternalServerError)
+		var data map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			log.Printf("解析JSON数据失败: %v\n", err)
+			http.Error(w, "无效的JSON数据", http.StatusBadRequest)
 			return
 		}
 
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("保存成功"))
-		log.Println("服务名称保存成功")
-	}
-}
+		// 判断是保存服务名称还是接口配置
+		if configType, ok := data["type"].(string); ok && configType == "interface_config" {
+			log.Println("处理接口配置保存请求")
+			// 保存接口配置
+			if name, ok := data["interface_name"].(string); ok {
+				if showLinks, ok := data["show_links"].(bool); ok {
+					store.SetInterfaceShowLinks(name, showLinks)
+					log.Printf("更新接口配置: %s = %v\n", name, showLinks)
+
+					// 保存到文件
+					if err := store.Save(); err != nil {
+						log.Printf("保存接口配置失败: %v\n", err)
+						http.Error(w, "保存失败", http.StatusInternalServerError)
+						return
+					}
 
-// 添加获取已保存服务名称的处理器
-func savedServiceNamesHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("接收到获取已保存配置的请求")
-	w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusOK)
+					w.Write([]byte("保存成功"))
+					log.Println("接口配置保存成功")
+					return
+				}
+			}
+			log.Println("无效的接口配置数据")
+			http.Error(w, "无效的接口配置数据", http.StatusBadRequest)
+			return
+		} else {
+			log.Println("处理服务名称保存请求")
+			// 保存服务名称（使用已解析的数据）
+			serviceID, ok1 := data["service_id"].(string)
+			name, ok2 := data["name"].(string)
+
+			if !ok1 || !ok2 {
+				log.Println("无效的服务名称数据")
+				http.Error(w, "无效的服务名称数据", http.StatusBadRequest)
+				return
+			}
 
-	// 准备服务名称数据
-	var nameMappings []ServiceNameMapping
-	for serviceID, name := range serviceNames {
-		nameMappings = append(nameMappings, ServiceNameMapping{
-			Service_id: serviceID,
-			Name:       name,
-		})
-	}
+			// 更新内存中的映射
+			store.SetServiceName(serviceID, name)
+			log.Printf("更新服务名称映射: %s = %s\n", serviceID, name)
 
-	// 准备接口配置数据
-	var interfaceConfigsData []InterfaceConfig
-	for name, showLinks := range interfaceConfigs {
-		interfaceConfigsData = append(interfaceConfigsData, InterfaceConfig{
-			Name:      name,
-			ShowLinks: showLinks,
-		})
-	}
+			// 保存到文件
+			if err := store.Save(); err != nil {
+				log.Printf("保存服务名称失败: %v\n", err)
+				http.Error(w, "保存失败", http.StatusInternalServerError)
+				return
+			}
 
-	// 准备列配置数据
-	var columnConfigsData []ColumnConfig
-	for table, columns := range columnConfigs {
-		for column, visible := range columns {
-			columnConfigsData = append(columnConfigsData, ColumnConfig{
-				Table:   table,
-				Column:  column,
-				Visible: visible,
-			})
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("保存成功"))
+			log.Println("服务名称保存成功")
 		}
 	}
+}
 
-	// 准备URL路径数据
-	var urlPathMappings []URLPathMapping
-	for serviceID, path := range urlPaths {
-		urlPathMappings = append(urlPathMappings, URLPathMapping{
-			Service_id: serviceID,
-			Path:       path,
-		})
-	}
+// 添加获取已保存服务名称的处理器
+func savedServiceNamesHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到获取已保存配置的请求")
+		w.Header().Set("Content-Type", "application/json")
 
-	// 组合所有数据
-	data := map[string]interface{}{
-		"service_names":     nameMappings,
-		"interface_configs": interfaceConfigsData,
-		"column_configs":    columnConfigsData,
-		"url_paths":         urlPathMappings, // 添加URL路径数据
+		data := store.Snapshot()
+		json.NewEncoder(w).Encode(data)
+		log.Printf("返回已保存配置: 服务名称 %d 个, 接口配置 %d 个\n", len(data.ServiceNames), len(data.InterfaceConfigs))
 	}
-
-	json.NewEncoder(w).Encode(data)
-	log.Printf("返回已保存配置: 服务名称 %d 个, 接口配置 %d 个\n", len(nameMappings), len(interfaceConfigsData))
 }
 
 // 添加保存列配置的处理器
-func saveColumnConfigHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("接收到保存列配置的请求")
-	if r.Method != http.MethodPost {
-		log.Printf("请求方法错误: %s\n", r.Method)
-		http.Error(w, "只支持POST方法", http.StatusMethodNotAllowed)
-		return
-	}
+func saveColumnConfigHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到保存列配置的请求")
+		if r.Method != http.MethodPost {
+			log.Printf("请求方法错误: %s\n", r.Method)
+			http.Error(w, "只支持POST方法", http.StatusMethodNotAllowed)
+			return
+		}
 
-	var requestData struct {
-		Table         string          `json:"table"`
-		ColumnConfigs map[string]bool `json:"column_configs"`
-	}
+		var requestData struct {
+			Table         string          `json:"table"`
+			ColumnConfigs map[string]bool `json:"column_configs"`
+		}
 
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
-		log.Printf("解析JSON数据失败: %v\n", err)
-		http.Error(w, "无效的JSON数据", http.StatusBadRequest)
-		return
-	}
+		if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+			log.Printf("解析JSON数据失败: %v\n", err)
+			http.Error(w, "无效的JSON数据", http.StatusBadRequest)
+			return
+		}
 
-	// 更新内存中的配置 - 为所有表格类型统一配置
+		// 更新内存中的配置 - 为所有表格类型统一配置
 
-	// 修改为统一更新所有表格类型的配置
-	tableTypes := []string{"tcpv4", "tcpv6", "udpv4", "udpv6"}
-	for _, tableType := range tableTypes {
-		if columnConfigs[tableType] == nil {
-			columnConfigs[tableType] = make(map[string]bool)
+		// 修改为统一更新所有表格类型的配置
+		tableTypes := []string{"tcpv4", "tcpv6", "udpv4", "udpv6", "sctp", "raw"}
+		for _, tableType := range tableTypes {
+			for column, visible := range requestData.ColumnConfigs {
+				store.SetColumnVisible(tableType, column, visible)
+				log.Printf("更新列配置: %s.%s = %v\n", tableType, column, visible)
+			}
 		}
-		for column, visible := range requestData.ColumnConfigs {
-			columnConfigs[tableType][column] = visible
-			log.Printf("更新列配置: %s.%s = %v\n", tableType, column, visible)
+
+		// 保存到文件
+		if err := store.Save(); err != nil {
+			log.Printf("保存列配置失败: %v\n", err)
+			http.Error(w, "保存失败", http.StatusInternalServerError)
+			return
 		}
-	}
 
-	// 保存到文件
-	if err := saveServiceNames(); err != nil {
-		log.Printf("保存列配置失败: %v\n", err)
-		http.Error(w, "保存失败", http.StatusInternalServerError)
-		return
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("保存成功"))
+		log.Println("列配置保存成功")
 	}
-
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("保存成功"))
-	log.Println("列配置保存成功")
 }
 
 // 添加保存URL路径的处理器
-func saveURLPathHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("接收到保存URL路径的请求")
-	if r.Method != http.MethodPost {
-		log.Printf("请求方法错误: %s\n", r.Method)
-		http.Error(w, "只支持POST方法", http.StatusMethodNotAllowed)
-		return
-	}
+func saveURLPathHandler(store *Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("接收到保存URL路径的请求")
+		if r.Method != http.MethodPost {
+			log.Printf("请求方法错误: %s\n", r.Method)
+			http.Error(w, "只支持POST方法", http.StatusMethodNotAllowed)
+			return
+		}
 
-	var data struct {
-		ServiceID string `json:"service_id"`
-		Path      string `json:"path"`
-	}
+		var data struct {
+			ServiceID string `json:"service_id"`
+			Path      string `json:"path"`
+		}
 
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		log.Printf("解析JSON数据失败: %v\n", err)
-		http.Error(w, "无效的JSON数据", http.StatusBadRequest)
-		return
-	}
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			log.Printf("解析JSON数据失败: %v\n", err)
+			http.Error(w, "无效的JSON数据", http.StatusBadRequest)
+			return
+		}
 
-	// 确保路径以/开头
-	path := data.Path
-	if path == "" {
-		path = "/"
-	} else if !strings.HasPrefix(path, "/") {
-		path = "/" + path
-	}
+		// 确保路径以/开头
+		path := data.Path
+		if path == "" {
+			path = "/"
+		} else if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
 
-	// 更新内存中的映射
-	urlPaths[data.ServiceID] = path
-	log.Printf("更新URL路径映射: %s = %s\n", data.ServiceID, path)
+		// 更新内存中的映射
+		store.SetURLPath(data.ServiceID, path)
+		log.Printf("更新URL路径映射: %s = %s\n", data.ServiceID, path)
 
-	// 保存到文件
-	if err := saveServiceNames(); err != nil {
-		log.Printf("保存URL路径失败: %v\n", err)
-		http.Error(w, "保存失败", http.StatusInternalServerError)
-		return
-	}
+		// 保存到文件
+		if err := store.Save(); err != nil {
+			log.Printf("保存URL路径失败: %v\n", err)
+			http.Error(w, "保存失败", http.StatusInternalServerError)
+			return
+		}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("保存成功"))
-	log.Println("URL路径保存成功")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("保存成功"))
+		log.Println("URL路径保存成功")
+	}
 }
 
 // 生成随机端口处理函数
@@ -833,6 +879,29 @@ func getFreePortsInRange(count, startPort, endPort int) ([]int, error) {
 
 // 获取系统中当前正在使用的端口
 func getUsedPorts() (map[int]bool, error) {
+	if config.Collector == collectorSS {
+		return getUsedPortsFromSS()
+	}
+
+	entries, err := collectSockets(listenFilter)
+	if err != nil {
+		log.Printf("采集套接字失败，回退到ss命令: %v\n", err)
+		return getUsedPortsFromSS()
+	}
+
+	usedPorts := make(map[int]bool)
+	for _, entry := range entries {
+		// 原始套接字的端口字段是协议号，SCTP端口不占用TCP/UDP端口
+		if entry.Protocol != "tcp" && entry.Protocol != "udp" {
+			continue
+		}
+		usedPorts[entry.LocalPort] = true
+	}
+	return usedPorts, nil
+}
+
+// 使用ss命令获取正在使用的端口（/proc不可用时的备用方案）
+func getUsedPortsFromSS() (map[int]bool, error) {
 	usedPorts := make(map[int]bool)
 	
 	// 使用ss命令获取端口信息
@@ -872,6 +941,22 @@ func isPortFree(port int) bool {
 }
 
 func getServices() ([]Service, error) {
+	if config.Collector == collectorSS {
+		return getServicesFromSS()
+	}
+
+	// 直接从内核读取套接字（netlink或/proc/net），不依赖iproute2
+	entries, err := collectNamespaceSockets(listenFilter)
+	if err != nil {
+		log.Printf("采集套接字失败，回退到ss命令: %v\n", err)
+		return getServicesFromSS()
+	}
+
+	return buildServices(entries), nil
+}
+
+// 使用ss命令获取服务列表（/proc不可用时的备用方案）
+func getServicesFromSS() ([]Service, error) {
 	// 使用ss命令获取网络连接信息
 	cmd := exec.Command("ss", "-tulnp")
 	output, err := cmd.Output()
@@ -881,6 +966,7 @@ func getServices() ([]Service, error) {
 
 	lines := strings.Split(string(output), "\n")
 	var services []Service
+	resolver := newProcessResolver()
 
 	// 解析输出
 	for _, line := range lines {
@@ -928,30 +1014,23 @@ func getServices() ([]Service, error) {
 			ForeignAddr: "",
 			PID:         processInfo, // 保存完整进程信息用于悬停显示
 		}
+		service.RecvQ, _ = strconv.Atoi(fields[2])
+		service.SendQ, _ = strconv.Atoi(fields[3])
+		service.Saturated = isQueueSaturated(fields[1] == "LISTEN", service.RecvQ, service.SendQ)
+
+		// 根据ss输出中的pid补充进程详细信息
+		if pids := parseSSPIDs(processInfo); len(pids) > 0 {
+			service.Process = resolver.resolvePIDs(pids)
+		}
+		applyCgroupInfo(&service, resolver)
 
 		services = append(services, service)
 	}
 
+	annotateContainers(services)
 	return services, nil
 }
 
-func getServiceName(port string) string {
-	portMap := map[string]string{
-		"22":   "SSH",
-		"80":   "HTTP",
-		"443":  "HTTPS",
-		"3306": "MySQL",
-		"5432": "PostgreSQL",
-		"6379": "Redis",
-		"8080": "HTTP Alt",
-	}
-
-	if name, exists := portMap[port]; exists {
-		return name
-	}
-	return "Unknown Service"
-}
-
 func getServiceState(state string) string {
 	stateMap := map[string]string{
 		"LISTEN":     "Listening",
@@ -966,7 +1045,26 @@ func getServiceState(state string) string {
 	return state
 }
 
-func getNetworkInterfaces() ([]InterfaceInfo, error) {
+// 本机网卡地址加上公网IP。公网IP由暴露分析在后台定期刷新，这里只读取缓存，不请求外部服务
+func getNetworkInterfaces(exposure *ExposureAnalyzer) ([]InterfaceInfo, error) {
+	interfaces, err := getLocalInterfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	if publicIP := exposure.cachedPublicIP(); publicIP != "" {
+		interfaces = append(interfaces, InterfaceInfo{
+			Name: "公网",
+			IP:   publicIP,
+		})
+	}
+
+	log.Printf("获取到 %d 个网络接口\n", len(interfaces))
+	return interfaces, nil
+}
+
+// 获取本机网卡的IPv4地址，不包括公网IP
+func getLocalInterfaces() ([]InterfaceInfo, error) {
 	var interfaces []InterfaceInfo
 
 	// 获取网络接口信息
@@ -1025,19 +1123,12 @@ func getNetworkInterfaces() ([]InterfaceInfo, error) {
 		}
 	}
 
-	// 获取公网IP
-	publicIP, err := getPublicIP()
-	if err == nil && publicIP != "" {
-		interfaces = append(interfaces, InterfaceInfo{
-			Name: "公网",
-			IP:   publicIP,
-		})
-	}
-
-	log.Printf("获取到 %d 个网络接口\n", len(interfaces))
 	return interfaces, nil
 }
 
+// 获取公网IP使用的HTTP客户端，避免公网IP服务无响应时请求一直挂起
+var publicIPClient = &http.Client{Timeout: publicIPTimeout}
+
 // 获取公网IP地址
 func getPublicIP() (string, error) {
 	// 获取配置文件路径
@@ -1046,16 +1137,24 @@ func getPublicIP() (string, error) {
 	// 读取YAML配置获取公网IP服务地址
 	yamlConfig := &YAMLConfig{
 		ServiceConfig: []struct {
-			Addr     string `yaml:"addr"`
-			Port     int    `yaml:"port"`
-			Exclude  string `yaml:"exclude"`
-			GetIpUrl string `yaml:"get_ip_url"`
+			Addr             string `yaml:"addr"`
+			Port             int    `yaml:"port"`
+			Exclude          string `yaml:"exclude"`
+			GetIpUrl         string `yaml:"get_ip_url"`
+			Collector        string `yaml:"collector"`
+			DockerSocket     string `yaml:"docker_socket"`
+			ScanNamespaces   bool   `yaml:"scan_namespaces"`
+			StateDir         string `yaml:"state_dir"`
+			HistoryInterval  int    `yaml:"history_interval"`
+			HistoryRetention int    `yaml:"history_retention"`
 		}{
 			{
-				Addr:     "0.0.0.0", // 设置默认监听地址
-				Port:     10810,
-				Exclude:  "lo,br-,veth",
-				GetIpUrl: "https://4.ipw.cn", // 默认公网IP服务地址
+				Addr:         "0.0.0.0", // 设置默认监听地址
+				Port:         10810,
+				Exclude:      "lo,br-,veth",
+				GetIpUrl:     "https://4.ipw.cn", // 默认公网IP服务地址
+				Collector:    collectorAuto,
+				DockerSocket: defaultDockerSocket,
 			},
 		},
 	}
@@ -1076,7 +1175,7 @@ func getPublicIP() (string, error) {
 	}
 
 	// 发送HTTP请求获取公网IP
-	resp, err := http.Get(yamlConfig.ServiceConfig[0].GetIpUrl)
+	resp, err := publicIPClient.Get(yamlConfig.ServiceConfig[0].GetIpUrl)
 	if err != nil {
 		return "", err
 	}
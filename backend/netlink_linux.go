@@ -0,0 +1,147 @@
+//go:build linux
+
+package backend
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// sock_diag 协议常量（对应内核 include/uapi/linux/sock_diag.h 和 inet_diag.h）
+const (
+	netlinkSockDiag  = 4
+	sockDiagByFamily = 20
+
+	inetDiagReqSize = 56
+	inetDiagMsgSize = 72
+)
+
+// 通过 NETLINK_SOCK_DIAG 直接从内核获取TCP/UDP套接字，避免解析文本
+func collectNetlink(filter socketFilter) ([]socketEntry, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, netlinkSockDiag)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+	defer syscall.Close(fd)
+
+	var entries []socketEntry
+	queries := []struct {
+		protocol string
+		proto    uint8
+		states   uint32
+	}{
+		{"tcp", syscall.IPPROTO_TCP, filter.TCPStates},
+		{"udp", syscall.IPPROTO_UDP, filter.UDPStates},
+	}
+
+	seq := uint32(0)
+	for _, q := range queries {
+		if q.states == 0 {
+			continue
+		}
+		for _, family := range []uint8{syscall.AF_INET, syscall.AF_INET6} {
+			seq++
+			list, err := dumpInetDiag(fd, seq, family, q.proto, q.states)
+			if err != nil {
+				return nil, fmt.Errorf("sock_diag查询%s失败: %v", q.protocol, err)
+			}
+			for i := range list {
+				list[i].Protocol = q.protocol
+			}
+			entries = append(entries, list...)
+		}
+	}
+
+	return entries, nil
+}
+
+// 发送一次 SOCK_DIAG_BY_FAMILY 的dump请求并读取全部应答
+func dumpInetDiag(fd int, seq uint32, family, protocol uint8, states uint32) ([]socketEntry, error) {
+	req := make([]byte, syscall.NLMSG_HDRLEN+inetDiagReqSize)
+	binary.NativeEndian.PutUint32(req[0:4], uint32(len(req)))
+	binary.NativeEndian.PutUint16(req[4:6], sockDiagByFamily)
+	binary.NativeEndian.PutUint16(req[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(req[8:12], seq)
+
+	// struct inet_diag_req_v2：family, protocol, ext, pad, states, sockid（全零表示不限）
+	body := req[syscall.NLMSG_HDRLEN:]
+	body[0] = family
+	body[1] = protocol
+	binary.NativeEndian.PutUint32(body[4:8], states)
+
+	if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+		return nil, os.NewSyscallError("sendto", err)
+	}
+
+	var entries []socketEntry
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			return nil, os.NewSyscallError("recvfrom", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, msg := range msgs {
+			if msg.Header.Seq != seq {
+				continue
+			}
+			switch msg.Header.Type {
+			case syscall.NLMSG_DONE:
+				return entries, nil
+			case syscall.NLMSG_ERROR:
+				if len(msg.Data) >= 4 {
+					if errno := int32(binary.NativeEndian.Uint32(msg.Data[:4])); errno != 0 {
+						return nil, syscall.Errno(-errno)
+					}
+				}
+				return entries, nil
+			case sockDiagByFamily:
+				if entry, ok := parseInetDiagMsg(msg.Data); ok {
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}
+}
+
+// 解析 struct inet_diag_msg
+func parseInetDiagMsg(data []byte) (socketEntry, bool) {
+	if len(data) < inetDiagMsgSize {
+		return socketEntry{}, false
+	}
+
+	ipv6 := data[0] == syscall.AF_INET6
+	ipLen := net.IPv4len
+	if ipv6 {
+		ipLen = net.IPv6len
+	}
+
+	// inet_diag_sockid 中端口和地址均为网络字节序
+	localIP := make(net.IP, ipLen)
+	copy(localIP, data[8:8+ipLen])
+	remoteIP := make(net.IP, ipLen)
+	copy(remoteIP, data[24:24+ipLen])
+
+	return socketEntry{
+		IPv6:       ipv6,
+		State:      data[1],
+		LocalPort:  int(binary.BigEndian.Uint16(data[4:6])),
+		RemotePort: int(binary.BigEndian.Uint16(data[6:8])),
+		LocalIP:    localIP,
+		RemoteIP:   remoteIP,
+		RxQueue:    binary.NativeEndian.Uint32(data[56:60]),
+		TxQueue:    binary.NativeEndian.Uint32(data[60:64]),
+		UID:        int(binary.NativeEndian.Uint32(data[64:68])),
+		Inode:      uint64(binary.NativeEndian.Uint32(data[68:72])),
+		// 监听套接字的idiag_wqueue为backlog上限
+		BacklogKnown: true,
+	}, true
+}
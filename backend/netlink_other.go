@@ -0,0 +1,10 @@
+//go:build !linux
+
+package backend
+
+import "errors"
+
+// 非Linux系统不支持 NETLINK_SOCK_DIAG
+func collectNetlink(filter socketFilter) ([]socketEntry, error) {
+	return nil, errors.New("当前系统不支持netlink套接字采集")
+}
@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 由ip netns创建的命名空间挂载目录
+var netnsRunDir = "/run/netns"
+
+// 网络命名空间
+type netNamespace struct {
+	Inode uint64
+	Name  string // host、/run/netns下的名称，或 netns-<inode>
+	PID   int    // 位于该命名空间中的一个进程，0表示没有进程
+	Path  string // /run/netns/<name>，用于没有进程的命名空间
+}
+
+// 解析 net:[4026531992] 格式的命名空间链接
+func parseNetnsLink(link string) (uint64, bool) {
+	if !strings.HasPrefix(link, "net:[") || !strings.HasSuffix(link, "]") {
+		return 0, false
+	}
+	inode, err := strconv.ParseUint(link[5:len(link)-1], 10, 64)
+	return inode, err == nil
+}
+
+// 本进程所在的网络命名空间（视为主机命名空间）
+func hostNetnsInode() uint64 {
+	link, err := os.Readlink(filepath.Join(procRoot, "self", "ns", "net"))
+	if err != nil {
+		return 0
+	}
+	inode, _ := parseNetnsLink(link)
+	return inode
+}
+
+// 通过 /proc/*/ns/net 和 /run/netns 枚举所有不同的网络命名空间
+func listNetNamespaces() []netNamespace {
+	byInode := make(map[uint64]*netNamespace)
+
+	hostInode := hostNetnsInode()
+	if hostInode != 0 {
+		byInode[hostInode] = &netNamespace{Inode: hostInode, Name: "host"}
+	}
+
+	if pidDirs, err := os.ReadDir(procRoot); err == nil {
+		for _, pidDir := range pidDirs {
+			pid, err := strconv.Atoi(pidDir.Name())
+			if err != nil {
+				continue
+			}
+			link, err := os.Readlink(filepath.Join(procRoot, pidDir.Name(), "ns", "net"))
+			if err != nil {
+				continue
+			}
+			inode, ok := parseNetnsLink(link)
+			if !ok {
+				continue
+			}
+			ns := byInode[inode]
+			if ns == nil {
+				ns = &netNamespace{Inode: inode, Name: "netns-" + strconv.FormatUint(inode, 10)}
+				byInode[inode] = ns
+			}
+			// ReadDir按名称排序，取数值最小的PID作为代表进程
+			if ns.PID == 0 || pid < ns.PID {
+				ns.PID = pid
+			}
+		}
+	}
+
+	if entries, err := os.ReadDir(netnsRunDir); err == nil {
+		for _, entry := range entries {
+			path := filepath.Join(netnsRunDir, entry.Name())
+			inode, err := fileInode(path)
+			if err != nil {
+				continue
+			}
+			ns := byInode[inode]
+			if ns == nil {
+				ns = &netNamespace{Inode: inode}
+				byInode[inode] = ns
+			}
+			if ns.Name != "host" {
+				ns.Name = entry.Name()
+			}
+			ns.Path = path
+		}
+	}
+
+	namespaces := make([]netNamespace, 0, len(byInode))
+	for _, ns := range byInode {
+		namespaces = append(namespaces, *ns)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Inode < namespaces[j].Inode
+	})
+	return namespaces
+}
+
+// 采集单个命名空间中的套接字：切换到该命名空间后按配置的后端采集，
+// 这样netlink可以获取backlog；无法切换（例如缺少权限）时直接读 /proc/<pid>/net
+func (ns netNamespace) collect(filter socketFilter) ([]socketEntry, error) {
+	path := ns.Path
+	if path == "" {
+		path = filepath.Join(procRoot, strconv.Itoa(ns.PID), "ns", "net")
+	}
+	entries, err := collectInNetns(path, filter)
+	if err != nil && ns.PID != 0 {
+		return collectProcNet(filepath.Join(procRoot, strconv.Itoa(ns.PID), "net"), filter)
+	}
+	return entries, err
+}
+
+// 采集主机及（开启scan_namespaces时）其他网络命名空间中的套接字
+func collectNamespaceSockets(filter socketFilter) ([]socketEntry, error) {
+	entries, err := collectSockets(filter)
+	if err != nil || !config.ScanNamespaces {
+		return entries, err
+	}
+
+	hostInode := hostNetnsInode()
+	for i := range entries {
+		entries[i].Netns = "host"
+		entries[i].NetnsInode = hostInode
+	}
+
+	for _, ns := range listNetNamespaces() {
+		if ns.Inode == hostInode {
+			continue
+		}
+
+		list, err := ns.collect(filter)
+		if err != nil {
+			log.Printf("采集网络命名空间 %s 失败: %v\n", ns.Name, err)
+			continue
+		}
+		for i := range list {
+			list[i].Netns = ns.Name
+			list[i].NetnsInode = ns.Inode
+		}
+		entries = append(entries, list...)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,98 @@
+//go:build linux
+
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+)
+
+// 各架构的setns系统调用号（syscall包在部分架构上没有导出SYS_SETNS）
+var setnsTrap = map[string]uintptr{
+	"amd64":   308,
+	"386":     346,
+	"arm":     375,
+	"arm64":   268,
+	"riscv64": 268,
+	"loong64": 268,
+	"ppc64":   350,
+	"ppc64le": 350,
+	"s390x":   339,
+}
+
+func setns(fd uintptr) error {
+	trap, ok := setnsTrap[runtime.GOARCH]
+	if !ok {
+		return fmt.Errorf("不支持在 %s 架构上切换网络命名空间", runtime.GOARCH)
+	}
+	if _, _, errno := syscall.RawSyscall(trap, fd, syscall.CLONE_NEWNET, 0); errno != 0 {
+		return os.NewSyscallError("setns", errno)
+	}
+	return nil
+}
+
+// 在单独的goroutine中锁定线程并临时进入指定命名空间读取套接字表
+func collectInNetns(path string, filter socketFilter) ([]socketEntry, error) {
+	type result struct {
+		entries []socketEntry
+		err     error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		runtime.LockOSThread()
+
+		target, err := os.Open(path)
+		if err != nil {
+			runtime.UnlockOSThread()
+			done <- result{err: err}
+			return
+		}
+		defer target.Close()
+
+		orig, err := os.Open(filepath.Join(procRoot, "thread-self", "ns", "net"))
+		if err != nil {
+			runtime.UnlockOSThread()
+			done <- result{err: err}
+			return
+		}
+		defer orig.Close()
+
+		if err := setns(target.Fd()); err != nil {
+			runtime.UnlockOSThread()
+			done <- result{err: err}
+			return
+		}
+
+		// netlink套接字属于创建它的线程所在的命名空间
+		entries, collectErr := collectSocketsIn(filepath.Join(procRoot, "thread-self", "net"), filter)
+
+		// 无法切回原命名空间时保持线程锁定，goroutine退出后运行时会销毁该线程
+		if err := setns(orig.Fd()); err != nil {
+			done <- result{err: err}
+			return
+		}
+		runtime.UnlockOSThread()
+
+		done <- result{entries: entries, err: collectErr}
+	}()
+
+	r := <-done
+	return r.entries, r.err
+}
+
+// 获取文件的inode编号
+func fileInode(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, fmt.Errorf("无法获取 %s 的inode", path)
+	}
+	return stat.Ino, nil
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package backend
+
+import "errors"
+
+var errNetnsUnsupported = errors.New("当前系统不支持网络命名空间")
+
+func collectInNetns(path string, filter socketFilter) ([]socketEntry, error) {
+	return nil, errNetnsUnsupported
+}
+
+func fileInode(path string) (uint64, error) {
+	return 0, errNetnsUnsupported
+}
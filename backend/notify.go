@@ -0,0 +1,266 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// 通知方式
+const (
+	notifierWebhook = "webhook"
+	notifierSMTP    = "smtp"
+	notifierCommand = "command"
+)
+
+// 发送通知的默认超时时间
+const defaultNotifyTimeout = 10 * time.Second
+
+// 配置文件中的通知方式
+type NotifierConfig struct {
+	Name    string `yaml:"name"`
+	Type    string `yaml:"type"`    // webhook, smtp, command
+	Timeout string `yaml:"timeout"` // 例如 10s
+
+	// webhook：以JSON格式POST事件
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+
+	// smtp：发送邮件
+	SMTPAddr string   `yaml:"smtp_addr"` // host:port
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+
+	// command：执行本地命令，事件JSON通过标准输入传入
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args"`
+}
+
+// Notifier 把事件发送到外部系统
+type Notifier interface {
+	Name() string
+	Notify(event Event) error
+}
+
+// 根据配置创建通知方式
+func newNotifier(cfg NotifierConfig) (Notifier, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("通知方式缺少name")
+	}
+
+	timeout := defaultNotifyTimeout
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("通知方式 %s 的timeout无效: %v", cfg.Name, err)
+		}
+		timeout = d
+	}
+
+	switch cfg.Type {
+	case notifierWebhook:
+		if cfg.URL == "" {
+			return nil, fmt.Errorf("webhook通知 %s 缺少url", cfg.Name)
+		}
+		return &webhookNotifier{name: cfg.Name, url: cfg.URL, headers: cfg.Headers, client: &http.Client{Timeout: timeout}}, nil
+	case notifierSMTP:
+		if cfg.SMTPAddr == "" || cfg.From == "" || len(cfg.To) == 0 {
+			return nil, fmt.Errorf("smtp通知 %s 需要smtp_addr、from和to", cfg.Name)
+		}
+		return &smtpNotifier{name: cfg.Name, addr: cfg.SMTPAddr, username: cfg.Username, password: cfg.Password, from: cfg.From, to: cfg.To, timeout: timeout}, nil
+	case notifierCommand:
+		if cfg.Command == "" {
+			return nil, fmt.Errorf("command通知 %s 缺少command", cfg.Name)
+		}
+		return &commandNotifier{name: cfg.Name, command: cfg.Command, args: cfg.Args, timeout: timeout}, nil
+	}
+	return nil, fmt.Errorf("通知方式 %s 的类型 %q 无效，应为 webhook、smtp 或 command", cfg.Name, cfg.Type)
+}
+
+type webhookNotifier struct {
+	name    string
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (n *webhookNotifier) Name() string { return n.name }
+
+func (n *webhookNotifier) Notify(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range n.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type smtpNotifier struct {
+	name     string
+	addr     string
+	username string
+	password string
+	from     string
+	to       []string
+	timeout  time.Duration
+}
+
+func (n *smtpNotifier) Name() string { return n.name }
+
+func (n *smtpNotifier) Notify(event Event) error {
+	hostname, _ := os.Hostname()
+	subject := fmt.Sprintf("[port-monitor] %s: %s", hostname, event.Message)
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", n.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+
+	fmt.Fprintf(&msg, "主机: %s\r\n", hostname)
+	fmt.Fprintf(&msg, "时间: %s\r\n", event.Time.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&msg, "事件: %s\r\n", event.Type)
+	if event.Rule != "" {
+		fmt.Fprintf(&msg, "规则: %s\r\n", event.Rule)
+	}
+	fmt.Fprintf(&msg, "\r\n%s\r\n", event.Message)
+	if event.Service != nil && event.Service.PID != "" {
+		fmt.Fprintf(&msg, "\r\n进程: %s\r\n", event.Service.PID)
+	}
+
+	return n.send([]byte(msg.String()))
+}
+
+// 与smtp.SendMail的流程相同，但整个会话受timeout限制，服务器无响应时不会一直阻塞
+func (n *smtpNotifier) send(msg []byte) error {
+	host, _, err := net.SplitHostPort(n.addr)
+	if err != nil {
+		return err
+	}
+
+	dialer := net.Dialer{Timeout: n.timeout}
+	conn, err := dialer.Dial("tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(n.timeout)); err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if n.username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("smtp服务器不支持认证")
+		}
+		if err := client.Auth(smtp.PlainAuth("", n.username, n.password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	for _, to := range n.to {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+type commandNotifier struct {
+	name    string
+	command string
+	args    []string
+	timeout time.Duration
+}
+
+func (n *commandNotifier) Name() string { return n.name }
+
+// 事件JSON通过标准输入传入，常用字段同时以环境变量提供
+func (n *commandNotifier) Notify(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, n.command, n.args...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"PORT_MONITOR_EVENT="+event.Type,
+		"PORT_MONITOR_RULE="+event.Rule,
+		"PORT_MONITOR_MESSAGE="+event.Message,
+	)
+	if event.Service != nil {
+		cmd.Env = append(cmd.Env,
+			"PORT_MONITOR_SERVICE_ID="+serviceID(*event.Service),
+			"PORT_MONITOR_PROTOCOL="+event.Service.Protocol,
+			"PORT_MONITOR_ADDRESS="+event.Service.LocalAddr,
+			"PORT_MONITOR_PORT="+event.Service.LocalPort,
+			"PORT_MONITOR_PROCESS="+event.Service.Name,
+		)
+	}
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
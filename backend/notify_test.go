@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSMTPNotifier(t *testing.T, addr string, timeout time.Duration) Notifier {
+	t.Helper()
+	notifier, err := newNotifier(NotifierConfig{
+		Name:     "mail",
+		Type:     notifierSMTP,
+		Timeout:  timeout.String(),
+		SMTPAddr: addr,
+		From:     "monitor@example.com",
+		To:       []string{"ops@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return notifier
+}
+
+// 简单的SMTP服务器，返回收到的邮件内容
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if inData {
+				if line == ".\r\n" {
+					inData = false
+					received <- data.String()
+					reply("250 OK")
+				} else {
+					data.WriteString(line)
+				}
+				continue
+			}
+			switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+			case "EHLO", "MAIL", "RCPT":
+				reply("250 OK")
+			case "DATA":
+				inData = true
+				reply("354 Go ahead")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMTPNotifierSend(t *testing.T) {
+	addr, received := fakeSMTPServer(t)
+	notifier := testSMTPNotifier(t, addr, 5*time.Second)
+
+	event := Event{Type: eventListenerAdded, Message: "新增监听 0.0.0.0:8080", Time: time.Now()}
+	if err := notifier.Notify(event); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-received:
+		if !strings.Contains(msg, "To: ops@example.com") || !strings.Contains(msg, "新增监听 0.0.0.0:8080") {
+			t.Errorf("邮件内容 = %q", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("SMTP服务器没有收到邮件")
+	}
+}
+
+func TestSMTPNotifierTimeout(t *testing.T) {
+	// 接受连接但从不响应的服务器
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-stop
+	}()
+
+	notifier := testSMTPNotifier(t, listener.Addr().String(), 200*time.Millisecond)
+	done := make(chan error, 1)
+	go func() {
+		done <- notifier.Notify(Event{Type: eventListenerAdded, Message: "test", Time: time.Now()})
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("服务器无响应时应该返回错误")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP通知没有在超时时间内返回")
+	}
+}
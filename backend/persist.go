@@ -0,0 +1,91 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// 数据文件保留的历史版本数量：data.json.1 为最近一次保存前的版本
+const dataBackupCount = 3
+
+func backupPath(path string, generation int) string {
+	return fmt.Sprintf("%s.%d", path, generation)
+}
+
+// 原子写入文件：先写入同目录下的临时文件并fsync，再重命名覆盖目标文件，
+// 最后fsync所在目录使重命名落盘。任何一步失败时原文件保持不变
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // 重命名成功后文件已不存在，删除会静默失败
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// fsync目录。部分平台不支持对目录fsync，此时忽略错误
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	d.Sync()
+	return nil
+}
+
+// 轮转备份：path.N-1 -> path.N ... path -> path.1，最旧的一份被丢弃。
+// 当前文件通过硬链接保留为 path.1，保证轮转期间主文件始终存在
+func rotateBackups(path string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for i := count - 1; i >= 1; i-- {
+		if err := os.Rename(backupPath(path, i), backupPath(path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	first := backupPath(path, 1)
+	os.Remove(first)
+	if err := os.Link(path, first); err != nil {
+		// 文件系统不支持硬链接时退回到复制
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(first, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
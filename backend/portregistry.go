@@ -0,0 +1,166 @@
+package backend
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 用户自定义的端口注册表与config.yaml放在同一目录，格式与 /etc/services 相同
+const portRegistryFileName = "ports.txt"
+
+// 系统的服务名称数据库
+const systemServicesFile = "/etc/services"
+
+// 端口注册表的来源，后加载的覆盖先加载的
+const (
+	portSourceSystem  = "system"  // /etc/services
+	portSourceBuiltin = "builtin" // 内置的 ports.txt
+	portSourceFile    = "file"    // 用户的 ports.txt
+)
+
+//go:embed ports.txt
+var builtinPorts string
+
+// 端口注册表中的一项
+type PortEntry struct {
+	Name        string `json:"name"`
+	Port        int    `json:"port"`
+	Protocol    string `json:"protocol"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source"`
+}
+
+// PortRegistry 端口到服务名称的映射
+type PortRegistry struct {
+	mu      sync.RWMutex
+	entries map[string]PortEntry // 端口/协议 -> 服务
+}
+
+func portKey(port int, protocol string) string {
+	return strconv.Itoa(port) + "/" + strings.ToLower(protocol)
+}
+
+// NewPortRegistry 依次加载 /etc/services、内置数据和用户的注册表文件
+func NewPortRegistry(path string) *PortRegistry {
+	r := &PortRegistry{entries: make(map[string]PortEntry)}
+
+	if f, err := os.Open(systemServicesFile); err == nil {
+		r.load(f, portSourceSystem)
+		f.Close()
+	}
+	r.load(strings.NewReader(builtinPorts), portSourceBuiltin)
+
+	if f, err := os.Open(path); err == nil {
+		n := r.load(f, portSourceFile)
+		f.Close()
+		log.Printf("从 %s 加载了 %d 个端口\n", path, n)
+	} else if !os.IsNotExist(err) {
+		log.Printf("读取端口注册表失败: %v\n", err)
+	}
+	return r
+}
+
+// 端口注册表文件默认路径
+func portRegistryPath() string {
+	return filepath.Join(filepath.Dir(getConfigPath()), portRegistryFileName)
+}
+
+// 解析 /etc/services 格式的数据：<名称> <端口>/<协议> [别名...] [# 说明]
+func (r *PortRegistry) load(reader io.Reader, source string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := 0
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		description := ""
+		if i := strings.Index(line, "#"); i >= 0 {
+			description = strings.TrimSpace(line[i+1:])
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		portText, protocol, ok := strings.Cut(fields[1], "/")
+		if !ok {
+			continue
+		}
+		port, err := strconv.Atoi(portText)
+		if err != nil || port <= 0 || port > 65535 {
+			continue
+		}
+
+		entry := PortEntry{Name: fields[0], Port: port, Protocol: strings.ToLower(protocol), Description: description, Source: source}
+		key := portKey(port, entry.Protocol)
+		// /etc/services 中同一端口可能有多个名称，保留第一个
+		if existing, ok := r.entries[key]; ok && existing.Source == source && source == portSourceSystem {
+			continue
+		}
+		r.entries[key] = entry
+		count++
+	}
+	return count
+}
+
+// Lookup 查找端口对应的服务
+func (r *PortRegistry) Lookup(port string, protocol string) (PortEntry, bool) {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return PortEntry{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entry, ok := r.entries[portKey(n, protocol)]
+	return entry, ok
+}
+
+// Entries 返回全部注册的端口
+func (r *PortRegistry) Entries() []PortEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := make([]PortEntry, 0, len(r.entries))
+	for _, entry := range r.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Port != entries[j].Port {
+			return entries[i].Port < entries[j].Port
+		}
+		return entries[i].Protocol < entries[j].Protocol
+	})
+	return entries
+}
+
+// 端口注册表，?port= 查询单个端口（可以用 ?protocol= 指定协议，默认tcp）
+func portRegistryHandler(registry *PortRegistry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries := []PortEntry{}
+		if port := r.URL.Query().Get("port"); port != "" {
+			protocol := r.URL.Query().Get("protocol")
+			if protocol == "" {
+				protocol = "tcp"
+			}
+			if entry, ok := registry.Lookup(port, protocol); ok {
+				entries = append(entries, entry)
+			}
+		} else {
+			entries = registry.Entries()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
@@ -0,0 +1,453 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 探测方式
+const (
+	probeTCP  = "tcp"
+	probeHTTP = "http"
+	probeUDP  = "udp"
+)
+
+// 探测结果
+const (
+	probeUp   = "up"
+	probeDown = "down"
+)
+
+const (
+	defaultProbeInterval = 30 // 秒
+	defaultProbeTimeout  = 3 * time.Second
+	probeConcurrency     = 16
+	maxProbeResponse     = 4096 // 读取响应的最大字节数
+)
+
+// 配置文件中的探测配置
+type ProbeConfig struct {
+	Interval int               `yaml:"interval"` // 秒，0为默认值30，小于0表示不探测
+	Timeout  string            `yaml:"timeout"`  // 例如 3s
+	Include  []ListenerMatcher `yaml:"include"`  // 只探测匹配的监听，为空表示全部
+	Exclude  []ListenerMatcher `yaml:"exclude"`
+	UDP      []UDPProbeConfig  `yaml:"udp"` // UDP没有连接，只探测这里配置的端口
+}
+
+// UDP请求/响应探测
+type UDPProbeConfig struct {
+	ListenerMatcher `yaml:",inline"`
+	Payload         string `yaml:"payload"` // 发送的内容，以 hex: 开头表示十六进制
+	Expect          string `yaml:"expect"`  // 响应中应包含的内容，为空表示收到任意响应即可
+}
+
+// 一个监听的最近一次探测结果
+type ProbeResult struct {
+	ServiceID  string    `json:"service_id"`
+	Type       string    `json:"type"`   // tcp, http, udp
+	Target     string    `json:"target"` // 探测的地址或URL
+	Status     string    `json:"status"` // up, down
+	Latency    float64   `json:"latency_ms"`
+	StatusCode int       `json:"status_code,omitempty"` // HTTP状态码
+	Error      string    `json:"error,omitempty"`
+	Time       time.Time `json:"time"`
+	Failures   int       `json:"failures"` // 连续失败次数
+	LastUp     time.Time `json:"last_up,omitempty"`
+}
+
+type udpProbe struct {
+	UDPProbeConfig
+	payload []byte
+}
+
+// Prober 定期探测监听是否可以正常访问
+type Prober struct {
+	interval time.Duration
+	timeout  time.Duration
+	include  []ListenerMatcher
+	exclude  []ListenerMatcher
+	udp      []udpProbe
+	store    *Store
+	sampler  *Sampler
+
+	mu      sync.RWMutex
+	results map[string]*ProbeResult
+}
+
+// NewProber 根据配置创建探测器，配置有误的UDP探测会被忽略
+func NewProber(cfg ProbeConfig, store *Store, sampler *Sampler) *Prober {
+	p := &Prober{
+		interval: time.Duration(cfg.Interval) * time.Second,
+		timeout:  defaultProbeTimeout,
+		store:    store,
+		sampler:  sampler,
+		results:  make(map[string]*ProbeResult),
+	}
+	if cfg.Interval == 0 {
+		p.interval = defaultProbeInterval * time.Second
+	}
+	if cfg.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Timeout); err == nil && d > 0 {
+			p.timeout = d
+		} else {
+			log.Printf("探测的timeout无效，使用默认值 %s: %s\n", defaultProbeTimeout, cfg.Timeout)
+		}
+	}
+
+	p.include = validMatchers(cfg.Include)
+	p.exclude = validMatchers(cfg.Exclude)
+
+	for _, udp := range cfg.UDP {
+		probe, err := newUDPProbe(udp)
+		if err != nil {
+			log.Printf("忽略UDP探测 %s: %v\n", udp.ListenerMatcher, err)
+			continue
+		}
+		p.udp = append(p.udp, probe)
+	}
+	return p
+}
+
+func validMatchers(matchers []ListenerMatcher) []ListenerMatcher {
+	var valid []ListenerMatcher
+	for _, matcher := range matchers {
+		if err := validateListenerMatcher(matcher); err != nil {
+			log.Printf("忽略探测的匹配条件 %s: %v\n", matcher, err)
+			continue
+		}
+		valid = append(valid, matcher)
+	}
+	return valid
+}
+
+func newUDPProbe(cfg UDPProbeConfig) (udpProbe, error) {
+	if cfg.Port == 0 {
+		return udpProbe{}, fmt.Errorf("缺少port")
+	}
+	if err := validateListenerMatcher(cfg.ListenerMatcher); err != nil {
+		return udpProbe{}, err
+	}
+	probe := udpProbe{UDPProbeConfig: cfg, payload: []byte(cfg.Payload)}
+	if strings.HasPrefix(cfg.Payload, "hex:") {
+		payload, err := hex.DecodeString(strings.TrimPrefix(cfg.Payload, "hex:"))
+		if err != nil {
+			return udpProbe{}, fmt.Errorf("无效的payload: %v", err)
+		}
+		probe.payload = payload
+	}
+	return probe, nil
+}
+
+// Start 在后台开始定期探测
+func (p *Prober) Start() {
+	if p.interval <= 0 {
+		log.Println("健康探测已禁用")
+		return
+	}
+
+	go func() {
+		p.probeAll()
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			p.probeAll()
+		}
+	}()
+}
+
+// 需要探测的监听及探测方式
+type probeTask struct {
+	service Service
+	kind    string
+	target  string
+	udp     *udpProbe
+}
+
+func (p *Prober) selected(service Service) bool {
+	// 其他网络命名空间中的监听无法从本机直接访问
+	if service.Netns != "" && service.Netns != "host" {
+		return false
+	}
+	for _, matcher := range p.exclude {
+		if matcher.matches(service) {
+			return false
+		}
+	}
+	if len(p.include) == 0 {
+		return true
+	}
+	for _, matcher := range p.include {
+		if matcher.matches(service) {
+			return true
+		}
+	}
+	return false
+}
+
+// 通配地址通过回环地址探测
+func probeHost(addr string) string {
+	switch addr {
+	case "0.0.0.0", "*":
+		return "127.0.0.1"
+	case "::":
+		return "::1"
+	}
+	return normalizeAddr(addr)
+}
+
+func (p *Prober) tasks(services []Service) []probeTask {
+	var tasks []probeTask
+	for _, service := range services {
+		if !p.selected(service) {
+			continue
+		}
+		address := net.JoinHostPort(probeHost(service.LocalAddr), service.LocalPort)
+
+		switch service.Protocol {
+		case "tcp":
+			// 保存了URL路径的服务使用HTTP探测
+			if path, ok := p.store.URLPath(serviceID(service)); ok {
+				tasks = append(tasks, probeTask{service: service, kind: probeHTTP, target: address + path})
+			} else {
+				tasks = append(tasks, probeTask{service: service, kind: probeTCP, target: address})
+			}
+		case "udp":
+			for i := range p.udp {
+				if p.udp[i].matches(service) {
+					tasks = append(tasks, probeTask{service: service, kind: probeUDP, target: address, udp: &p.udp[i]})
+					break
+				}
+			}
+		}
+	}
+	return tasks
+}
+
+func (p *Prober) probeAll() {
+	snapshot, err := currentSnapshot(p.sampler)
+	if err != nil {
+		log.Printf("获取服务列表失败，跳过本次探测: %v\n", err)
+		return
+	}
+
+	tasks := p.tasks(snapshot.Services)
+	results := make([]*ProbeResult, len(tasks))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for i, task := range tasks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, task probeTask) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = p.probe(task)
+		}(i, task)
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	current := make(map[string]*ProbeResult, len(results))
+	for _, result := range results {
+		previous := p.results[result.ServiceID]
+		if previous != nil {
+			result.LastUp = previous.LastUp
+			if result.Status == probeDown {
+				result.Failures = previous.Failures + 1
+			}
+			if previous.Status != result.Status {
+				log.Printf("探测 %s %s: %s -> %s %s\n", result.Type, result.Target, previous.Status, result.Status, result.Error)
+			}
+		} else if result.Status == probeDown {
+			result.Failures = 1
+			log.Printf("探测 %s %s 失败: %s\n", result.Type, result.Target, result.Error)
+		}
+		if result.Status == probeUp {
+			result.LastUp = result.Time
+		}
+		current[result.ServiceID] = result
+	}
+	// 已经消失的监听不再保留结果
+	p.results = current
+}
+
+func (p *Prober) probe(task probeTask) *ProbeResult {
+	result := &ProbeResult{
+		ServiceID: serviceID(task.service),
+		Type:      task.kind,
+		Target:    task.target,
+		Time:      time.Now(),
+	}
+
+	start := time.Now()
+	var err error
+	switch task.kind {
+	case probeTCP:
+		err = p.probeTCP(task.target)
+	case probeHTTP:
+		result.Target, result.StatusCode, err = p.probeHTTP(task.service, task.target)
+	case probeUDP:
+		err = p.probeUDP(task.target, task.udp)
+	}
+	result.Latency = float64(time.Since(start).Microseconds()) / 1000
+
+	result.Status = probeUp
+	if err != nil {
+		result.Status = probeDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (p *Prober) probeTCP(address string) error {
+	conn, err := net.DialTimeout("tcp", address, p.timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HTTP探测：443/8443端口直接使用HTTPS，其他端口先尝试HTTP，连接失败时改用HTTPS。
+// 5xx状态码视为失败
+func (p *Prober) probeHTTP(service Service, target string) (string, int, error) {
+	schemes := []string{"http", "https"}
+	if service.LocalPort == "443" || service.LocalPort == "8443" {
+		schemes = []string{"https"}
+	}
+
+	client := &http.Client{
+		Timeout: p.timeout,
+		Transport: &http.Transport{
+			// 探测只关心服务是否响应，证书由TLS检查负责
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	var url string
+	var err error
+	for i, scheme := range schemes {
+		url = scheme + "://" + target
+		var code int
+		code, err = httpStatus(client, url)
+		if err != nil {
+			continue
+		}
+		// 向HTTPS端口发送HTTP请求时通常返回400，改用HTTPS再试一次
+		if code == http.StatusBadRequest && i+1 < len(schemes) {
+			next := schemes[i+1] + "://" + target
+			if nextCode, nextErr := httpStatus(client, next); nextErr == nil {
+				return next, nextCode, statusError(nextCode)
+			}
+		}
+		return url, code, statusError(code)
+	}
+	return url, 0, err
+}
+
+func httpStatus(client *http.Client, url string) (int, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxProbeResponse))
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func statusError(code int) error {
+	if code >= 500 {
+		return fmt.Errorf("HTTP状态码 %d", code)
+	}
+	return nil
+}
+
+func (p *Prober) probeUDP(address string, probe *udpProbe) error {
+	conn, err := net.DialTimeout("udp", address, p.timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(p.timeout))
+	if _, err := conn.Write(probe.payload); err != nil {
+		return err
+	}
+	buf := make([]byte, maxProbeResponse)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return fmt.Errorf("没有收到响应: %v", err)
+	}
+	if probe.Expect != "" && !bytes.Contains(buf[:n], []byte(probe.Expect)) {
+		return fmt.Errorf("响应中不包含 %q", probe.Expect)
+	}
+	return nil
+}
+
+// Annotate 把最近一次探测结果标注到服务上
+func (p *Prober) Annotate(services []Service) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for i := range services {
+		if services[i].Netns != "" && services[i].Netns != "host" {
+			continue
+		}
+		if result, ok := p.results[serviceID(services[i])]; ok {
+			copied := *result
+			services[i].Health = &copied
+		}
+	}
+}
+
+// Results 返回全部探测结果，失败的在前
+func (p *Prober) Results() []ProbeResult {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	results := make([]ProbeResult, 0, len(p.results))
+	for _, result := range p.results {
+		results = append(results, *result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Status != results[j].Status {
+			return results[i].Status == probeDown
+		}
+		return results[i].ServiceID < results[j].ServiceID
+	})
+	return results
+}
+
+// 探测结果，?service_id= 只返回指定服务的结果
+func probesHandler(prober *Prober) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := prober.Results()
+		if id := r.URL.Query().Get("service_id"); id != "" {
+			filtered := []ProbeResult{}
+			for _, result := range results {
+				if result.ServiceID == id {
+					filtered = append(filtered, result)
+				}
+			}
+			results = filtered
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(results)
+	}
+}
@@ -0,0 +1,238 @@
+package backend
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testProber(t *testing.T, cfg ProbeConfig, sampler *Sampler) *Prober {
+	t.Helper()
+	if cfg.Timeout == "" {
+		cfg.Timeout = "1s"
+	}
+	store := NewStore(filepath.Join(t.TempDir(), dataFileName))
+	return NewProber(cfg, store, sampler)
+}
+
+// 把监听地址转换为服务
+func probeService(t *testing.T, protocol string, addr net.Addr) Service {
+	t.Helper()
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Service{Protocol: protocol, LocalAddr: host, LocalPort: port}
+}
+
+func TestProbeHTTPFallsBackToHTTPS(t *testing.T) {
+	// 向HTTPS端口发送HTTP请求时返回400
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	prober := testProber(t, ProbeConfig{}, nil)
+	service := probeService(t, "tcp", server.Listener.Addr())
+	target := server.Listener.Addr().String() + "/health"
+
+	if code, err := httpStatus(http.DefaultClient, "http://"+target); err != nil || code != http.StatusBadRequest {
+		t.Fatalf("HTTP请求 = %d %v, want 400", code, err)
+	}
+
+	url, code, err := prober.probeHTTP(service, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://"+target || code != http.StatusOK {
+		t.Errorf("probeHTTP() = %s %d, want https://%s 200", url, code, target)
+	}
+}
+
+func TestProbeHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		up   bool
+	}{
+		{http.StatusOK, true},
+		{http.StatusFound, true},
+		{http.StatusBadRequest, true}, // HTTPS也失败时保留HTTP的结果
+		{http.StatusNotFound, true},
+		{499, true},
+		{http.StatusInternalServerError, false},
+		{http.StatusServiceUnavailable, false},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tt.code == http.StatusFound {
+				http.Redirect(w, r, "/login", tt.code)
+				return
+			}
+			w.WriteHeader(tt.code)
+		}))
+
+		prober := testProber(t, ProbeConfig{}, nil)
+		service := probeService(t, "tcp", server.Listener.Addr())
+		result := prober.probe(probeTask{service: service, kind: probeHTTP, target: server.Listener.Addr().String() + "/"})
+		server.Close()
+
+		if result.StatusCode != tt.code || (result.Status == probeUp) != tt.up {
+			t.Errorf("状态码 %d: status=%s status_code=%d error=%s", tt.code, result.Status, result.StatusCode, result.Error)
+		}
+		if !strings.HasPrefix(result.Target, "http://") {
+			t.Errorf("状态码 %d: target = %s, want http://", tt.code, result.Target)
+		}
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+
+	prober := testProber(t, ProbeConfig{}, nil)
+	service := probeService(t, "tcp", listener.Addr())
+	result := prober.probe(probeTask{service: service, kind: probeTCP, target: address})
+	if result.Status != probeUp || result.ServiceID != serviceID(service) || result.Error != "" {
+		t.Errorf("监听存在时 result = %+v", result)
+	}
+
+	listener.Close()
+	result = prober.probe(probeTask{service: service, kind: probeTCP, target: address})
+	if result.Status != probeDown || result.Error == "" {
+		t.Errorf("监听关闭后 result = %+v", result)
+	}
+}
+
+// 收到ping时回复pong，其他内容不回复
+func udpPongServer(t *testing.T) net.PacketConn {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if string(buf[:n]) == "ping" {
+				conn.WriteTo([]byte("+pong\r\n"), addr)
+			}
+		}
+	}()
+	return conn
+}
+
+func TestProbeUDP(t *testing.T) {
+	server := udpPongServer(t)
+	address := server.LocalAddr().String()
+	prober := testProber(t, ProbeConfig{Timeout: "200ms"}, nil)
+
+	tests := []struct {
+		payload string
+		expect  string
+		errText string
+	}{
+		{"ping", "pong", ""},
+		{"ping", "", ""}, // 收到任意响应即可
+		{"hex:70696e67", "pong", ""},
+		{"ping", "PONG", "响应中不包含"},
+		{"hello", "", "没有收到响应"},
+	}
+	for _, tt := range tests {
+		probe, err := newUDPProbe(UDPProbeConfig{ListenerMatcher: ListenerMatcher{Port: 1}, Payload: tt.payload, Expect: tt.expect})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = prober.probeUDP(address, &probe)
+		switch {
+		case tt.errText == "" && err != nil:
+			t.Errorf("payload=%q expect=%q: %v", tt.payload, tt.expect, err)
+		case tt.errText != "" && (err == nil || !strings.Contains(err.Error(), tt.errText)):
+			t.Errorf("payload=%q expect=%q: err = %v, want %q", tt.payload, tt.expect, err, tt.errText)
+		}
+	}
+}
+
+func TestProberUDPTasks(t *testing.T) {
+	server := udpPongServer(t)
+	service := probeService(t, "udp", server.LocalAddr())
+	port, _ := strconv.Atoi(service.LocalPort)
+
+	sampler := NewSampler(0, nil)
+	sampler.latest = &Snapshot{Time: time.Now(), Services: []Service{
+		service,
+		{Protocol: "udp", LocalAddr: "127.0.0.1", LocalPort: "1"}, // 没有配置的UDP端口不探测
+	}}
+	prober := testProber(t, ProbeConfig{UDP: []UDPProbeConfig{
+		{ListenerMatcher: ListenerMatcher{Port: port}, Payload: "ping", Expect: "pong"},
+		{ListenerMatcher: ListenerMatcher{Port: 53}, Payload: "hex:zz"}, // 无效的payload，被忽略
+	}}, sampler)
+	if len(prober.udp) != 1 {
+		t.Fatalf("有效的UDP探测 = %d, want 1", len(prober.udp))
+	}
+
+	prober.probeAll()
+	results := prober.Results()
+	if len(results) != 1 || results[0].Type != probeUDP || results[0].Status != probeUp {
+		t.Errorf("results = %+v, want 只探测配置的UDP端口", results)
+	}
+}
+
+func TestProberFailureCount(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := probeService(t, "tcp", listener.Addr())
+
+	sampler := NewSampler(0, nil)
+	sampler.latest = &Snapshot{Time: time.Now(), Services: []Service{service}}
+	prober := testProber(t, ProbeConfig{}, sampler)
+
+	prober.probeAll()
+	first := prober.Results()
+	if len(first) != 1 || first[0].Status != probeUp || first[0].Failures != 0 || first[0].LastUp.IsZero() {
+		t.Fatalf("第一次探测 results = %+v", first)
+	}
+	lastUp := first[0].LastUp
+
+	// 监听关闭后每次探测失败，连续失败次数递增，保留最后一次正常的时间
+	listener.Close()
+	for want := 1; want <= 3; want++ {
+		prober.probeAll()
+		results := prober.Results()
+		if len(results) != 1 || results[0].Status != probeDown || results[0].Failures != want {
+			t.Fatalf("第 %d 次失败 results = %+v", want, results)
+		}
+		if !results[0].LastUp.Equal(lastUp) {
+			t.Errorf("last_up = %s, want %s", results[0].LastUp, lastUp)
+		}
+	}
+
+	services := []Service{service}
+	prober.Annotate(services)
+	if services[0].Health == nil || services[0].Health.Failures != 3 {
+		t.Errorf("health = %+v, want 连续失败3次", services[0].Health)
+	}
+
+	// 已经消失的监听不再保留结果
+	sampler.latest = &Snapshot{Time: time.Now()}
+	prober.probeAll()
+	if results := prober.Results(); len(results) != 0 {
+		t.Errorf("监听消失后 results = %+v", results)
+	}
+}
@@ -0,0 +1,354 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 读取不到时使用的时钟频率（USER_HZ），主流架构均为100
+const defaultClockTicks = 100
+
+// 辅助向量中的 AT_CLKTCK，即 sysconf(_SC_CLK_TCK) 的来源
+const auxvClockTicks = 17
+
+var (
+	clockTicksOnce  sync.Once
+	clockTicksValue int64
+)
+
+// 内核向用户态导出的时钟频率，/proc/<pid>/stat 中的时间以此为单位
+func clockTicks() int64 {
+	clockTicksOnce.Do(func() {
+		clockTicksValue = defaultClockTicks
+		data, err := os.ReadFile(filepath.Join(procRoot, "self", "auxv"))
+		if err != nil {
+			return
+		}
+		if hz, ok := parseAuxvClockTicks(data, strconv.IntSize/8); ok {
+			clockTicksValue = hz
+		}
+	})
+	return clockTicksValue
+}
+
+// 解析 /proc/self/auxv：按本机字节序排列的（类型, 值）字对，wordSize为指针宽度
+func parseAuxvClockTicks(data []byte, wordSize int) (int64, bool) {
+	word := func(b []byte) uint64 {
+		if wordSize == 8 {
+			return binary.NativeEndian.Uint64(b)
+		}
+		return uint64(binary.NativeEndian.Uint32(b))
+	}
+	for i := 0; i+2*wordSize <= len(data); i += 2 * wordSize {
+		key, value := word(data[i:]), word(data[i+wordSize:])
+		if key == 0 {
+			break
+		}
+		if key == auxvClockTicks && value > 0 {
+			return int64(value), true
+		}
+	}
+	return 0, false
+}
+
+// 套接字所属进程的详细信息
+type ProcessInfo struct {
+	PID       int       `json:"pid"`
+	PPID      int       `json:"ppid"`
+	Comm      string    `json:"comm"`
+	Exe       string    `json:"exe"`
+	Cmdline   string    `json:"cmdline"`
+	UID       int       `json:"uid"`
+	User      string    `json:"user"`
+	StartTime time.Time `json:"start_time"`
+	PIDs      []int     `json:"pids"` // 共享该套接字的全部进程（如prefork模式的worker）
+}
+
+// 套接字的持有进程
+type socketOwner struct {
+	PID  int
+	FD   int
+	Comm string
+}
+
+// 遍历 /proc/<pid>/fd 建立套接字inode到进程的映射
+func socketOwners() map[uint64][]socketOwner {
+	owners := make(map[uint64][]socketOwner)
+
+	pidDirs, err := os.ReadDir(procRoot)
+	if err != nil {
+		return owners
+	}
+
+	for _, pidDir := range pidDirs {
+		pid, err := strconv.Atoi(pidDir.Name())
+		if err != nil {
+			continue
+		}
+
+		fdDir := filepath.Join(procRoot, pidDir.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			// 进程已退出或无权限访问
+			continue
+		}
+
+		comm := ""
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			fdNum, _ := strconv.Atoi(fd.Name())
+
+			if comm == "" {
+				comm = readProcComm(pid)
+			}
+
+			owners[inode] = append(owners[inode], socketOwner{PID: pid, FD: fdNum, Comm: comm})
+		}
+	}
+
+	for inode := range owners {
+		sort.Slice(owners[inode], func(i, j int) bool {
+			return owners[inode][i].PID < owners[inode][j].PID
+		})
+	}
+
+	return owners
+}
+
+// 按ss的 users:(("name",pid=1,fd=3)) 格式输出进程信息，便于前端悬停显示
+func formatSocketOwners(owners []socketOwner) string {
+	if len(owners) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(owners))
+	for _, owner := range owners {
+		parts = append(parts, fmt.Sprintf("(\"%s\",pid=%d,fd=%d)", owner.Comm, owner.PID, owner.FD))
+	}
+	return "users:(" + strings.Join(parts, ",") + ")"
+}
+
+// 进程解析器，一次采集周期内复用inode映射和进程信息
+type processResolver struct {
+	owners    map[uint64][]socketOwner // 首次使用时才遍历 /proc/*/fd
+	processes map[int]*ProcessInfo
+	cgroups   map[int]cgroupInfo
+	bootTime  time.Time
+}
+
+func newProcessResolver() *processResolver {
+	return &processResolver{
+		processes: make(map[int]*ProcessInfo),
+		cgroups:   make(map[int]cgroupInfo),
+		bootTime:  readBootTime(),
+	}
+}
+
+func (r *processResolver) ownersOf(inode uint64) []socketOwner {
+	if r.owners == nil {
+		r.owners = socketOwners()
+	}
+	return r.owners[inode]
+}
+
+// 返回持有指定inode的进程列表（ss格式），无持有进程时返回空字符串
+func (r *processResolver) Owners(inode uint64) string {
+	return formatSocketOwners(r.ownersOf(inode))
+}
+
+// 解析持有指定套接字inode的主进程
+func (r *processResolver) Resolve(inode uint64) *ProcessInfo {
+	owners := r.ownersOf(inode)
+	if len(owners) == 0 {
+		return nil
+	}
+
+	var pids []int
+	for _, owner := range owners {
+		if len(pids) == 0 || pids[len(pids)-1] != owner.PID {
+			pids = append(pids, owner.PID)
+		}
+	}
+	return r.resolvePIDs(pids)
+}
+
+// 在共享同一套接字的进程中选出主进程：父进程不在集合内的那个，通常是prefork的master
+func (r *processResolver) resolvePIDs(pids []int) *ProcessInfo {
+	shared := make(map[int]bool, len(pids))
+	for _, pid := range pids {
+		shared[pid] = true
+	}
+
+	var primary *ProcessInfo
+	for _, pid := range pids {
+		info := r.Process(pid)
+		if info == nil {
+			continue
+		}
+		if primary == nil || (shared[primary.PPID] && !shared[info.PPID]) {
+			primary = info
+		}
+	}
+	if primary == nil {
+		return nil
+	}
+
+	result := *primary
+	result.PIDs = pids
+	return &result
+}
+
+// 读取单个进程的元数据，结果在解析器内缓存
+func (r *processResolver) Process(pid int) *ProcessInfo {
+	if info, ok := r.processes[pid]; ok {
+		return info
+	}
+
+	info := readProcessInfo(pid, r.bootTime)
+	r.processes[pid] = info
+	return info
+}
+
+// 从 /proc/<pid> 读取进程元数据，进程已退出时返回nil
+func readProcessInfo(pid int, bootTime time.Time) *ProcessInfo {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return nil
+	}
+
+	info := &ProcessInfo{PID: pid, UID: -1}
+
+	// comm可能包含空格和括号，以最后一个右括号为界
+	text := string(stat)
+	open := strings.IndexByte(text, '(')
+	end := strings.LastIndexByte(text, ')')
+	if open < 0 || end < open {
+		return nil
+	}
+	info.Comm = text[open+1 : end]
+
+	// 右括号之后依次为 state ppid ... starttime（第22个字段）
+	fields := strings.Fields(text[end+1:])
+	if len(fields) > 1 {
+		info.PPID, _ = strconv.Atoi(fields[1])
+	}
+	if len(fields) > 19 && !bootTime.IsZero() {
+		if ticks, err := strconv.ParseInt(fields[19], 10, 64); err == nil {
+			hz := clockTicks()
+			// 分成整秒和余数计算，避免运行时间较长时溢出
+			info.StartTime = bootTime.Add(time.Duration(ticks/hz)*time.Second + time.Duration(ticks%hz)*time.Second/time.Duration(hz))
+		}
+	}
+
+	info.Exe, _ = os.Readlink(filepath.Join(dir, "exe"))
+
+	if cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline")); err == nil {
+		info.Cmdline = strings.TrimSpace(strings.ReplaceAll(string(cmdline), "\x00", " "))
+	}
+
+	if status, err := os.Open(filepath.Join(dir, "status")); err == nil {
+		scanner := bufio.NewScanner(status)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "Uid:") {
+				continue
+			}
+			// Uid: 实际 有效 保存 文件系统，取有效UID
+			if uids := strings.Fields(line); len(uids) > 2 {
+				info.UID, _ = strconv.Atoi(uids[2])
+			}
+			break
+		}
+		status.Close()
+	}
+	if info.UID >= 0 {
+		info.User = lookupUserName(info.UID)
+	}
+
+	return info
+}
+
+// 读取进程名称
+func readProcComm(pid int) string {
+	data, _ := os.ReadFile(filepath.Join(procRoot, strconv.Itoa(pid), "comm"))
+	return strings.TrimSpace(string(data))
+}
+
+// 从 /proc/stat 读取系统启动时间
+func readBootTime() time.Time {
+	file, err := os.Open(filepath.Join(procRoot, "stat"))
+	if err != nil {
+		return time.Time{}
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == "btime" {
+			if sec, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				return time.Unix(sec, 0)
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// 用户名缓存，避免每次采集都读取 /etc/passwd
+var (
+	userNameCache   = make(map[int]string)
+	userNameCacheMu sync.Mutex
+)
+
+func lookupUserName(uid int) string {
+	userNameCacheMu.Lock()
+	defer userNameCacheMu.Unlock()
+
+	if name, ok := userNameCache[uid]; ok {
+		return name
+	}
+
+	name := strconv.Itoa(uid)
+	if u, err := user.LookupId(name); err == nil {
+		name = u.Username
+	}
+	userNameCache[uid] = name
+	return name
+}
+
+// 从ss输出的进程信息中提取PID列表
+var ssPIDRegex = regexp.MustCompile(`pid=(\d+)`)
+
+func parseSSPIDs(processInfo string) []int {
+	var pids []int
+	seen := make(map[int]bool)
+	for _, match := range ssPIDRegex.FindAllStringSubmatch(processInfo, -1) {
+		pid, err := strconv.Atoi(match[1])
+		if err != nil || seen[pid] {
+			continue
+		}
+		seen[pid] = true
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
+}
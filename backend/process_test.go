@@ -0,0 +1,50 @@
+package backend
+
+import (
+	"encoding/binary"
+	"os"
+	"testing"
+)
+
+func auxv(wordSize int, pairs ...uint64) []byte {
+	data := make([]byte, len(pairs)*wordSize)
+	for i, v := range pairs {
+		if wordSize == 8 {
+			binary.NativeEndian.PutUint64(data[i*8:], v)
+		} else {
+			binary.NativeEndian.PutUint32(data[i*4:], uint32(v))
+		}
+	}
+	return data
+}
+
+func TestParseAuxvClockTicks(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		wordSize int
+		want     int64
+		ok       bool
+	}{
+		{"64位", auxv(8, 6, 4096, 17, 250, 0, 0), 8, 250, true},
+		{"32位", auxv(4, 6, 4096, 17, 1000, 0, 0), 4, 1000, true},
+		{"AT_NULL之后的项忽略", auxv(8, 6, 4096, 0, 0, 17, 250), 8, 0, false},
+		{"没有AT_CLKTCK", auxv(8, 6, 4096, 0, 0), 8, 0, false},
+		{"截断的数据", auxv(8, 17, 250)[:12], 8, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseAuxvClockTicks(tt.data, tt.wordSize)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: got %d, %v, want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestClockTicks(t *testing.T) {
+	if _, err := os.Stat("/proc/self/auxv"); err != nil {
+		t.Skip("没有 /proc/self/auxv")
+	}
+	if hz := clockTicks(); hz <= 0 {
+		t.Errorf("clockTicks() = %d", hz)
+	}
+}
@@ -0,0 +1,293 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 套接字状态码（对应内核 include/net/tcp_states.h）
+const (
+	tcpEstablished = 0x01
+	tcpSynSent     = 0x02
+	tcpSynRecv     = 0x03
+	tcpFinWait1    = 0x04
+	tcpFinWait2    = 0x05
+	tcpTimeWait    = 0x06
+	tcpClose       = 0x07
+	tcpCloseWait   = 0x08
+	tcpLastAck     = 0x09
+	tcpListen      = 0x0A
+	tcpClosing     = 0x0B
+)
+
+// 内核状态码到ss状态名称的映射，保持与ss输出一致以便复用getServiceState
+var socketStateNames = map[uint8]string{
+	tcpEstablished: "ESTAB",
+	tcpSynSent:     "SYN-SENT",
+	tcpSynRecv:     "SYN-RECV",
+	tcpFinWait1:    "FIN-WAIT-1",
+	tcpFinWait2:    "FIN-WAIT-2",
+	tcpTimeWait:    "TIME-WAIT",
+	tcpClose:       "UNCONN",
+	tcpCloseWait:   "CLOSE-WAIT",
+	tcpLastAck:     "LAST-ACK",
+	tcpListen:      "LISTEN",
+	tcpClosing:     "CLOSING",
+}
+
+// proc文件系统挂载点，可替换为测试用的样例目录
+var procRoot = "/proc"
+
+// 从内核读取到的单个套接字
+type socketEntry struct {
+	Protocol   string
+	IPv6       bool
+	State      uint8
+	LocalIP    net.IP
+	LocalPort  int
+	RemoteIP   net.IP
+	RemotePort int
+	TxQueue    uint32
+	RxQueue    uint32
+	UID        int
+	Inode      uint64
+	Netns      string // 所在网络命名空间，仅在扫描多个命名空间时设置
+	NetnsInode uint64
+	// 监听套接字的TxQueue是否为backlog上限：netlink可以获取，/proc/net中始终为0
+	BacklogKnown bool
+}
+
+// 套接字状态过滤条件，按位表示需要保留的状态
+type socketFilter struct {
+	TCPStates  uint32
+	UDPStates  uint32
+	SCTPStates uint32
+	RawStates  uint32
+}
+
+// 与 ss -l 一致：TCP/SCTP只取LISTEN，UDP和原始套接字只取未连接的套接字
+var listenFilter = socketFilter{
+	TCPStates:  1 << tcpListen,
+	UDPStates:  1 << tcpClose,
+	SCTPStates: 1 << tcpListen,
+	RawStates:  1 << tcpClose,
+}
+
+func (f socketFilter) match(e socketEntry) bool {
+	var mask uint32
+	switch e.Protocol {
+	case "tcp":
+		mask = f.TCPStates
+	case "udp":
+		mask = f.UDPStates
+	case "sctp":
+		mask = f.SCTPStates
+	case "raw":
+		mask = f.RawStates
+	}
+	return mask&(1<<e.State) != 0
+}
+
+// /proc/net 下的套接字表文件
+type procNetSource struct {
+	File     string
+	Protocol string
+	IPv6     bool
+}
+
+var procNetSources = []procNetSource{
+	{File: "tcp", Protocol: "tcp", IPv6: false},
+	{File: "tcp6", Protocol: "tcp", IPv6: true},
+	{File: "udp", Protocol: "udp", IPv6: false},
+	{File: "udp6", Protocol: "udp", IPv6: true},
+}
+
+// 读取指定目录（通常是 /proc/net 或 /proc/<pid>/net）下的套接字表
+func collectProcNet(netDir string, filter socketFilter) ([]socketEntry, error) {
+	var entries []socketEntry
+	found := false
+
+	for _, src := range procNetSources {
+		file, err := os.Open(filepath.Join(netDir, src.File))
+		if err != nil {
+			// 内核未启用IPv6时tcp6/udp6不存在
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+
+		list, err := parseProcNet(file, src.Protocol, src.IPv6)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析%s失败: %v", filepath.Join(netDir, src.File), err)
+		}
+
+		for _, entry := range list {
+			if filter.match(entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("未找到套接字表: %s", netDir)
+	}
+	return append(entries, collectProcNetExtra(netDir, filter)...), nil
+}
+
+// 解析 /proc/net/tcp 格式的套接字表
+func parseProcNet(r io.Reader, protocol string, ipv6 bool) ([]socketEntry, error) {
+	var entries []socketEntry
+	scanner := bufio.NewScanner(r)
+
+	// 跳过表头
+	if !scanner.Scan() {
+		return entries, scanner.Err()
+	}
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 {
+			continue
+		}
+
+		localIP, localPort, err := parseHexAddr(fields[1], ipv6)
+		if err != nil {
+			return nil, err
+		}
+		remoteIP, remotePort, err := parseHexAddr(fields[2], ipv6)
+		if err != nil {
+			return nil, err
+		}
+
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("无效的状态码 %q", fields[3])
+		}
+
+		queues := strings.SplitN(fields[4], ":", 2)
+		if len(queues) != 2 {
+			return nil, fmt.Errorf("无效的队列字段 %q", fields[4])
+		}
+		txQueue, _ := strconv.ParseUint(queues[0], 16, 32)
+		rxQueue, _ := strconv.ParseUint(queues[1], 16, 32)
+
+		uid, _ := strconv.Atoi(fields[7])
+		inode, _ := strconv.ParseUint(fields[9], 10, 64)
+
+		entries = append(entries, socketEntry{
+			Protocol:   protocol,
+			IPv6:       ipv6,
+			State:      uint8(state),
+			LocalIP:    localIP,
+			LocalPort:  localPort,
+			RemoteIP:   remoteIP,
+			RemotePort: remotePort,
+			TxQueue:    uint32(txQueue),
+			RxQueue:    uint32(rxQueue),
+			UID:        uid,
+			Inode:      inode,
+		})
+	}
+
+	return entries, scanner.Err()
+}
+
+// 解析形如 0100007F:0035 的地址，内核按32位字以本机字节序输出
+func parseHexAddr(s string, ipv6 bool) (net.IP, int, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return nil, 0, fmt.Errorf("无效的地址 %q", s)
+	}
+
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (ipv6 && len(raw) != net.IPv6len) || (!ipv6 && len(raw) != net.IPv4len) {
+		return nil, 0, fmt.Errorf("无效的地址 %q", s)
+	}
+
+	ip := make(net.IP, len(raw))
+	for i := 0; i < len(raw); i += 4 {
+		binary.BigEndian.PutUint32(ip[i:], binary.NativeEndian.Uint32(raw[i:]))
+	}
+
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("无效的端口 %q", s)
+	}
+
+	return ip, int(port), nil
+}
+
+// 按ss的习惯格式化地址，IPv4映射地址保留 ::ffff: 前缀以便前端归入IPv6
+func formatSocketIP(ip net.IP, ipv6 bool) string {
+	if ipv6 && len(ip) == net.IPv6len && ip.To4() != nil {
+		return "::ffff:" + ip.To4().String()
+	}
+	return ip.String()
+}
+
+// accept队列达到backlog的该比例时视为饱和
+const queueSaturationRatio = 0.8
+
+// 判断监听套接字的accept队列是否接近backlog上限，backlog未知（为0）时不做判断
+func isQueueSaturated(listening bool, recvQ, sendQ int) bool {
+	if !listening || sendQ <= 0 {
+		return false
+	}
+	return float64(recvQ) >= float64(sendQ)*queueSaturationRatio
+}
+
+// 将内核套接字转换为服务列表
+func buildServices(entries []socketEntry) []Service {
+	resolver := newProcessResolver()
+	services := make([]Service, 0, len(entries))
+
+	for _, entry := range entries {
+		process := resolver.Resolve(entry.Inode)
+		processName := "N/A"
+		if process != nil && process.Comm != "" {
+			processName = process.Comm
+		}
+
+		// 监听和未连接的套接字没有对端地址
+		foreignAddr := ""
+		if entry.State != tcpListen && entry.State != tcpClose {
+			foreignAddr = formatHostPort(entry.RemoteIP, entry.IPv6, entry.RemotePort)
+		}
+
+		service := Service{
+			Name:        processName,
+			Protocol:    entry.Protocol,
+			LocalAddr:   formatSocketIP(entry.LocalIP, entry.IPv6),
+			LocalPort:   strconv.Itoa(entry.LocalPort),
+			State:       getServiceState(socketStateNames[entry.State]),
+			ForeignAddr: foreignAddr,
+			PID:         resolver.Owners(entry.Inode),
+			Process:     process,
+			Netns:       entry.Netns,
+			NetnsInode:  entry.NetnsInode,
+			RecvQ:       int(entry.RxQueue),
+			SendQ:       int(entry.TxQueue),
+		}
+		listening := (entry.Protocol == "tcp" || entry.Protocol == "sctp") && entry.State == tcpListen
+		// /proc/net 中监听套接字的Send-Q始终为0，不能当作backlog
+		service.BacklogUnknown = listening && !entry.BacklogKnown
+		service.Saturated = isQueueSaturated(listening && entry.BacklogKnown, service.RecvQ, service.SendQ)
+		applyCgroupInfo(&service, resolver)
+
+		services = append(services, service)
+	}
+
+	annotateContainers(services)
+	return services
+}
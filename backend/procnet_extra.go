@@ -0,0 +1,105 @@
+package backend
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 原始套接字表，格式与 /proc/net/tcp 相同，端口字段为IP协议号
+var procNetRawSources = []procNetSource{
+	{File: "raw", Protocol: "raw", IPv6: false},
+	{File: "raw6", Protocol: "raw", IPv6: true},
+}
+
+// 读取原始套接字和SCTP端点。对应的内核模块未加载时文件不存在，直接跳过
+func collectProcNetExtra(netDir string, filter socketFilter) []socketEntry {
+	var entries []socketEntry
+
+	for _, src := range procNetRawSources {
+		file, err := os.Open(filepath.Join(netDir, src.File))
+		if err != nil {
+			continue
+		}
+		list, err := parseProcNet(file, src.Protocol, src.IPv6)
+		file.Close()
+		if err != nil {
+			log.Printf("解析%s失败: %v\n", filepath.Join(netDir, src.File), err)
+			continue
+		}
+		for _, entry := range list {
+			if filter.match(entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	if file, err := os.Open(filepath.Join(netDir, "sctp", "eps")); err == nil {
+		list, err := parseProcNetSCTP(file)
+		file.Close()
+		if err != nil {
+			log.Printf("解析SCTP端点失败: %v\n", err)
+		}
+		for _, entry := range list {
+			if filter.match(entry) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	return entries
+}
+
+// 解析 /proc/net/sctp/eps，多宿主端点的每个本地地址各生成一条记录
+func parseProcNetSCTP(r io.Reader) ([]socketEntry, error) {
+	var entries []socketEntry
+	scanner := bufio.NewScanner(r)
+
+	// 跳过表头
+	if !scanner.Scan() {
+		return entries, scanner.Err()
+	}
+
+	for scanner.Scan() {
+		// ENDPT SOCK STY SST HBKT LPORT UID INODE LADDRS...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 9 {
+			continue
+		}
+
+		state, err := strconv.ParseUint(fields[3], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("无效的状态码 %q", fields[3])
+		}
+		port, err := strconv.Atoi(fields[5])
+		if err != nil {
+			return nil, fmt.Errorf("无效的端口 %q", fields[5])
+		}
+		uid, _ := strconv.Atoi(fields[6])
+		inode, _ := strconv.ParseUint(fields[7], 10, 64)
+
+		for _, addr := range fields[8:] {
+			ip := net.ParseIP(addr)
+			if ip == nil {
+				continue
+			}
+			entries = append(entries, socketEntry{
+				Protocol:  "sctp",
+				IPv6:      ip.To4() == nil,
+				State:     uint8(state),
+				LocalIP:   ip,
+				LocalPort: port,
+				UID:       uid,
+				Inode:     inode,
+			})
+		}
+	}
+
+	return entries, scanner.Err()
+}
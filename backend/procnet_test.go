@@ -0,0 +1,230 @@
+package backend
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testdata/proc 中的样例按小端机器（x86、arm64）的内核输出编写
+func skipOnBigEndian(t *testing.T) {
+	t.Helper()
+	if binary.NativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("样例文件按小端字节序编写")
+	}
+}
+
+func useProcFixtures(t *testing.T) {
+	t.Helper()
+	orig := procRoot
+	procRoot = filepath.Join("testdata", "proc")
+	t.Cleanup(func() { procRoot = orig })
+}
+
+func TestParseHexAddr(t *testing.T) {
+	skipOnBigEndian(t)
+
+	tests := []struct {
+		in   string
+		ipv6 bool
+		ip   string
+		port int
+	}{
+		{"0100007F:0CEA", false, "127.0.0.1", 3306},
+		{"00000000:0016", false, "0.0.0.0", 22},
+		{"0F02000A:A1B2", false, "10.0.2.15", 41394},
+		{"00000000000000000000000000000000:0050", true, "::", 80},
+		{"00000000000000000000000001000000:1F90", true, "::1", 8080},
+		{"B80D0120000000000000000001000000:01BB", true, "2001:db8::1", 443},
+		{"0000000000000000FFFF0000010200C0:2328", true, "192.0.2.1", 9000},
+	}
+	for _, tt := range tests {
+		ip, port, err := parseHexAddr(tt.in, tt.ipv6)
+		if err != nil {
+			t.Errorf("parseHexAddr(%q): %v", tt.in, err)
+			continue
+		}
+		if !ip.Equal(net.ParseIP(tt.ip)) || port != tt.port {
+			t.Errorf("parseHexAddr(%q) = %s:%d, want %s:%d", tt.in, ip, port, tt.ip, tt.port)
+		}
+	}
+}
+
+func TestParseHexAddrInvalid(t *testing.T) {
+	for _, in := range []string{
+		"0100007F",                              // 缺少端口
+		"0100007F:XYZ",                          // 端口不是十六进制
+		"0100007:0016",                          // 奇数位十六进制
+		"00000000000000000000000001000000:0016", // IPv4表中出现IPv6地址
+	} {
+		if _, _, err := parseHexAddr(in, false); err == nil {
+			t.Errorf("parseHexAddr(%q) 应该返回错误", in)
+		}
+	}
+	if _, _, err := parseHexAddr("0100007F:0016", true); err == nil {
+		t.Error("IPv6表中的IPv4地址应该返回错误")
+	}
+}
+
+func TestFormatSocketIP(t *testing.T) {
+	skipOnBigEndian(t)
+
+	ip, _, _ := parseHexAddr("0000000000000000FFFF0000010200C0:2328", true)
+	if got := formatSocketIP(ip, true); got != "::ffff:192.0.2.1" {
+		t.Errorf("IPv4映射地址 = %q, want ::ffff:192.0.2.1", got)
+	}
+	ip, _, _ = parseHexAddr("0100007F:0CEA", false)
+	if got := formatSocketIP(ip, false); got != "127.0.0.1" {
+		t.Errorf("IPv4地址 = %q, want 127.0.0.1", got)
+	}
+}
+
+func TestParseProcNet(t *testing.T) {
+	skipOnBigEndian(t)
+
+	file, err := os.Open(filepath.Join("testdata", "proc", "net", "tcp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	entries, err := parseProcNet(file, "tcp", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("解析到 %d 个套接字, want 3", len(entries))
+	}
+
+	listen := entries[0]
+	if listen.State != tcpListen || socketStateNames[listen.State] != "LISTEN" {
+		t.Errorf("状态 = %#x, want LISTEN", listen.State)
+	}
+	if listen.UID != 999 || listen.Inode != 2000 {
+		t.Errorf("uid/inode = %d/%d, want 999/2000", listen.UID, listen.Inode)
+	}
+	if listen.RxQueue != 3 || listen.TxQueue != 0 {
+		t.Errorf("队列 = %d/%d, want 3/0", listen.RxQueue, listen.TxQueue)
+	}
+
+	established := entries[2]
+	if established.State != tcpEstablished || socketStateNames[established.State] != "ESTAB" {
+		t.Errorf("状态 = %#x, want ESTAB", established.State)
+	}
+	if !established.RemoteIP.Equal(net.IPv4(127, 0, 0, 1)) || established.RemotePort != 54321 {
+		t.Errorf("对端 = %s:%d, want 127.0.0.1:54321", established.RemoteIP, established.RemotePort)
+	}
+	if established.TxQueue != 16 || established.Inode != 2002 {
+		t.Errorf("tx_queue/inode = %d/%d, want 16/2002", established.TxQueue, established.Inode)
+	}
+}
+
+func TestParseProcNetInvalid(t *testing.T) {
+	header := "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	for _, line := range []string{
+		"   0: 0100007F:0CEA 00000000:0000 ZZ 00000000:00000000 00:00000000 00000000 0 0 1",
+		"   0: 0100007F:0CEA 00000000:0000 0A 00000000 00:00000000 00000000 0 0 1",
+		"   0: 0100007F 00000000:0000 0A 00000000:00000000 00:00000000 00000000 0 0 1",
+	} {
+		if _, err := parseProcNet(strings.NewReader(header+line+"\n"), "tcp", false); err == nil {
+			t.Errorf("parseProcNet(%q) 应该返回错误", line)
+		}
+	}
+
+	// 字段不足的行直接跳过
+	entries, err := parseProcNet(strings.NewReader(header+"   0: 0100007F:0CEA\n"), "tcp", false)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("字段不足的行: entries=%d err=%v", len(entries), err)
+	}
+}
+
+func TestCollectProcNet(t *testing.T) {
+	skipOnBigEndian(t)
+	useProcFixtures(t)
+
+	entries, err := collectProcNet(filepath.Join(procRoot, "net"), listenFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[uint64]struct {
+		protocol string
+		ipv6     bool
+		addr     string
+		port     int
+		uid      int
+	}{
+		2000: {"tcp", false, "127.0.0.1", 3306, 999},
+		2001: {"tcp", false, "0.0.0.0", 22, 0},
+		3001: {"tcp", true, "::", 80, 33},
+		3002: {"tcp", true, "::1", 8080, 1000},
+		3003: {"tcp", true, "2001:db8::1", 443, 0},
+		3004: {"tcp", true, "::ffff:192.0.2.1", 9000, 0},
+		4001: {"udp", false, "0.0.0.0", 53, 0},
+		4002: {"udp", false, "127.0.0.1", 323, 0},
+		5001: {"udp", true, "::", 5353, 105},
+	}
+	if len(entries) != len(want) {
+		t.Errorf("采集到 %d 个套接字, want %d（已建立的TCP连接和已连接的UDP套接字应被过滤）", len(entries), len(want))
+	}
+	for _, entry := range entries {
+		w, ok := want[entry.Inode]
+		if !ok {
+			t.Errorf("不应采集inode %d", entry.Inode)
+			continue
+		}
+		addr := formatSocketIP(entry.LocalIP, entry.IPv6)
+		if entry.Protocol != w.protocol || entry.IPv6 != w.ipv6 || addr != w.addr || entry.LocalPort != w.port || entry.UID != w.uid {
+			t.Errorf("inode %d = %s %v %s:%d uid=%d, want %s %v %s:%d uid=%d", entry.Inode,
+				entry.Protocol, entry.IPv6, addr, entry.LocalPort, entry.UID,
+				w.protocol, w.ipv6, w.addr, w.port, w.uid)
+		}
+	}
+}
+
+func TestCollectProcNetMissing(t *testing.T) {
+	if _, err := collectProcNet(filepath.Join("testdata", "missing"), listenFilter); err == nil {
+		t.Error("套接字表不存在时应该返回错误")
+	}
+}
+
+func TestBuildServicesBacklogUnknown(t *testing.T) {
+	skipOnBigEndian(t)
+	useProcFixtures(t)
+
+	entries, err := collectProcNet(filepath.Join(procRoot, "net"), listenFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, service := range buildServices(entries) {
+		listening := service.Protocol == "tcp"
+		if service.BacklogUnknown != listening {
+			t.Errorf("%s %s:%s backlog_unknown = %v, want %v", service.Protocol, service.LocalAddr, service.LocalPort, service.BacklogUnknown, listening)
+		}
+		if service.Saturated {
+			t.Errorf("%s:%s backlog未知时不应判断为饱和", service.LocalAddr, service.LocalPort)
+		}
+	}
+}
+
+func TestIsQueueSaturated(t *testing.T) {
+	tests := []struct {
+		listening    bool
+		recvQ, sendQ int
+		want         bool
+	}{
+		{true, 100, 128, false},
+		{true, 103, 128, true},
+		{true, 129, 128, true},
+		{true, 3, 0, false},
+		{false, 200, 128, false},
+	}
+	for _, tt := range tests {
+		if got := isQueueSaturated(tt.listening, tt.recvQ, tt.sendQ); got != tt.want {
+			t.Errorf("isQueueSaturated(%v, %d, %d) = %v, want %v", tt.listening, tt.recvQ, tt.sendQ, got, tt.want)
+		}
+	}
+}
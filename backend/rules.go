@@ -0,0 +1,391 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 规则的检查对象
+const (
+	ruleTargetService   = "service"
+	ruleTargetInterface = "interface"
+)
+
+// 规则的触发方式
+const (
+	ruleModeAny  = "any"  // 有对象满足条件时触发
+	ruleModeNone = "none" // 没有对象满足条件时触发
+)
+
+// 各检查对象在表达式中可以使用的字段
+var (
+	serviceRuleFields = []string{
+		"port", "protocol", "address", "process", "comm", "exe", "cmdline", "user", "uid", "pid",
+		"state", "netns", "container", "image", "unit", "baseline",
+		"recv_q", "send_q", "saturated", "loopback", "wildcard",
+	}
+	interfaceRuleFields = []string{"name", "ip"}
+)
+
+// 配置文件中的规则
+type RuleConfig struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Target      string   `yaml:"target"` // service（默认）或 interface
+	When        string   `yaml:"when"`   // 条件表达式
+	Mode        string   `yaml:"mode"`   // any（默认）或 none
+	Notifiers   []string `yaml:"notifiers"`
+	Debounce    string   `yaml:"debounce"` // 同一规则同类事件的最短通知间隔，例如 10m
+}
+
+// 规则的当前状态
+type RuleStatus struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Target      string    `json:"target"`
+	When        string    `json:"when"`
+	Mode        string    `json:"mode"`
+	Firing      bool      `json:"firing"`
+	Since       time.Time `json:"since,omitempty"` // 进入当前状态的时间
+	Matches     []string  `json:"matches"`         // 满足条件的对象
+	Evaluated   time.Time `json:"evaluated,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+type rule struct {
+	RuleConfig
+	expr     *Expr
+	debounce time.Duration
+
+	firing    bool
+	since     time.Time
+	matches   []string
+	evaluated time.Time
+	err       error
+}
+
+func (r *rule) status() RuleStatus {
+	status := RuleStatus{
+		Name:        r.Name,
+		Description: r.Description,
+		Target:      r.Target,
+		When:        r.When,
+		Mode:        r.Mode,
+		Firing:      r.firing,
+		Since:       r.since,
+		Matches:     append([]string{}, r.matches...),
+		Evaluated:   r.evaluated,
+	}
+	if r.err != nil {
+		status.Error = r.err.Error()
+	}
+	return status
+}
+
+// RuleEngine 在每次采样后计算规则，状态变化时通过事件引擎发送通知
+type RuleEngine struct {
+	mu       sync.Mutex
+	rules    []*rule
+	baseline *Baseline // 为baseline字段提供基线分类
+	events   *EventEngine
+}
+
+// NewRuleEngine 编译配置中的规则，有误的规则会被忽略
+func NewRuleEngine(configs []RuleConfig, baseline *Baseline, events *EventEngine) *RuleEngine {
+	e := &RuleEngine{baseline: baseline, events: events}
+	names := make(map[string]bool)
+
+	for i, cfg := range configs {
+		if cfg.Name == "" {
+			cfg.Name = "rule-" + strconv.Itoa(i+1)
+		}
+		if names[cfg.Name] {
+			log.Printf("忽略重复的规则: %s\n", cfg.Name)
+			continue
+		}
+		r, err := compileRule(cfg)
+		if err != nil {
+			log.Printf("忽略规则 %s: %v\n", cfg.Name, err)
+			continue
+		}
+		for _, name := range cfg.Notifiers {
+			if !events.HasNotifier(name) {
+				log.Printf("规则 %s 使用了未定义的通知方式: %s\n", cfg.Name, name)
+			}
+		}
+		names[cfg.Name] = true
+		e.rules = append(e.rules, r)
+	}
+
+	if len(e.rules) > 0 {
+		log.Printf("加载了 %d 条规则\n", len(e.rules))
+	}
+	return e
+}
+
+func compileRule(cfg RuleConfig) (*rule, error) {
+	if cfg.Target == "" {
+		cfg.Target = ruleTargetService
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = ruleModeAny
+	}
+
+	var fields []string
+	switch cfg.Target {
+	case ruleTargetService:
+		fields = serviceRuleFields
+	case ruleTargetInterface:
+		fields = interfaceRuleFields
+	default:
+		return nil, fmt.Errorf("无效的target %q，应为 service 或 interface", cfg.Target)
+	}
+	if cfg.Mode != ruleModeAny && cfg.Mode != ruleModeNone {
+		return nil, fmt.Errorf("无效的mode %q，应为 any 或 none", cfg.Mode)
+	}
+	if strings.TrimSpace(cfg.When) == "" {
+		return nil, fmt.Errorf("缺少when条件")
+	}
+
+	var debounce time.Duration
+	if cfg.Debounce != "" {
+		d, err := time.ParseDuration(cfg.Debounce)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("无效的debounce %q", cfg.Debounce)
+		}
+		debounce = d
+	}
+
+	expr, err := CompileExpr(cfg.When, fields)
+	if err != nil {
+		return nil, fmt.Errorf("解析条件失败: %v", err)
+	}
+	return &rule{RuleConfig: cfg, expr: expr, debounce: debounce, matches: []string{}}, nil
+}
+
+// 服务在规则表达式中的字段
+func serviceRuleValues(service Service) exprFields {
+	port, _ := strconv.ParseFloat(service.LocalPort, 64)
+	pid := float64(servicePID(service))
+	if pid == 0 {
+		pid, _ = strconv.ParseFloat(service.PID, 64)
+	}
+	ip := net.ParseIP(normalizeAddr(service.LocalAddr))
+
+	fields := exprFields{
+		"port":      port,
+		"protocol":  service.Protocol,
+		"address":   normalizeAddr(service.LocalAddr),
+		"process":   service.Name,
+		"comm":      "",
+		"exe":       "",
+		"cmdline":   "",
+		"user":      "",
+		"uid":       float64(-1),
+		"pid":       pid,
+		"state":     service.State,
+		"netns":     service.Netns,
+		"container": service.ContainerName,
+		"image":     service.ContainerImage,
+		"unit":      service.Unit,
+		"baseline":  service.Baseline,
+		"recv_q":    float64(service.RecvQ),
+		"send_q":    float64(service.SendQ),
+		"saturated": service.Saturated,
+		"loopback":  ip != nil && ip.IsLoopback(),
+		"wildcard":  isWildcardAddr(service.LocalAddr),
+	}
+	if service.Process != nil {
+		fields["comm"] = service.Process.Comm
+		fields["exe"] = service.Process.Exe
+		fields["cmdline"] = service.Process.Cmdline
+		fields["user"] = service.Process.User
+		fields["uid"] = float64(service.Process.UID)
+	}
+	return fields
+}
+
+func interfaceRuleValues(iface InterfaceInfo) exprFields {
+	return exprFields{"name": iface.Name, "ip": iface.IP}
+}
+
+// 规则检查的对象
+type ruleTarget struct {
+	label  string
+	fields exprFields
+}
+
+// Watch 订阅采样器，在每次采样后计算全部规则
+func (e *RuleEngine) Watch(sampler *Sampler) {
+	if len(e.rules) == 0 {
+		return
+	}
+	ch, _ := sampler.Subscribe()
+	go func() {
+		for snapshot := range ch {
+			e.Evaluate(snapshot)
+		}
+	}()
+}
+
+// Evaluate 使用快照中的服务列表和当前的网卡地址计算规则
+func (e *RuleEngine) Evaluate(snapshot *Snapshot) {
+	// 采样器的快照不包含基线分类，快照同时发给了其他订阅者，只标注副本
+	services := append([]Service(nil), snapshot.Services...)
+	if e.baseline != nil {
+		e.baseline.Evaluate(services)
+	}
+
+	targets := make(map[string][]ruleTarget)
+	for _, service := range services {
+		targets[ruleTargetService] = append(targets[ruleTargetService], ruleTarget{
+			label:  describeListener(service),
+			fields: serviceRuleValues(service),
+		})
+	}
+
+	var interfacesErr error
+	if e.hasTarget(ruleTargetInterface) {
+		// 不包括公网IP，避免每次采样都请求外部服务
+		interfaces, err := getLocalInterfaces()
+		interfacesErr = err
+		for _, iface := range interfaces {
+			targets[ruleTargetInterface] = append(targets[ruleTargetInterface], ruleTarget{
+				label:  iface.Name + " " + iface.IP,
+				fields: interfaceRuleValues(iface),
+			})
+		}
+	}
+
+	type trigger struct {
+		event     Event
+		notifiers []string
+		debounce  time.Duration
+	}
+	var fired []trigger
+	e.mu.Lock()
+	for _, r := range e.rules {
+		if r.Target == ruleTargetInterface && interfacesErr != nil {
+			r.err = fmt.Errorf("获取网卡地址失败: %v", interfacesErr)
+			r.evaluated = snapshot.Time
+			continue
+		}
+		if event, ok := r.evaluate(targets[r.Target], snapshot.Time); ok {
+			fired = append(fired, trigger{event: event, notifiers: r.Notifiers, debounce: r.debounce})
+		}
+	}
+	e.mu.Unlock()
+
+	for _, t := range fired {
+		e.events.Trigger(t.event, t.notifiers, t.debounce)
+	}
+}
+
+func (e *RuleEngine) hasTarget(target string) bool {
+	for _, r := range e.rules {
+		if r.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// 计算规则，规则开始触发、恢复或在触发期间有新的对象满足条件时返回事件
+func (r *rule) evaluate(targets []ruleTarget, now time.Time) (Event, bool) {
+	matches := []string{}
+	var evalErr error
+	for _, target := range targets {
+		ok, err := r.expr.Eval(target.fields)
+		if err != nil {
+			evalErr = err
+			continue
+		}
+		if ok {
+			matches = append(matches, target.label)
+		}
+	}
+	sort.Strings(matches)
+
+	firing := len(matches) > 0
+	if r.Mode == ruleModeNone {
+		firing = !firing
+	}
+
+	previous := make(map[string]bool)
+	for _, label := range r.matches {
+		previous[label] = true
+	}
+	var added []string
+	for _, label := range matches {
+		if !previous[label] {
+			added = append(added, label)
+		}
+	}
+
+	wasFiring := r.firing
+	if r.since.IsZero() || firing != wasFiring {
+		r.since = now
+	}
+	r.firing, r.matches, r.evaluated, r.err = firing, matches, now, evalErr
+
+	event := Event{Time: now, Rule: r.Name}
+	switch {
+	case firing && !wasFiring:
+		event.Type = eventRuleFiring
+		event.Targets = matches
+		if r.Mode == ruleModeNone {
+			event.Message = fmt.Sprintf("规则 %s 触发: 没有满足 %s 的%s", r.Name, r.When, ruleTargetName(r.Target))
+		} else {
+			event.Message = fmt.Sprintf("规则 %s 触发: %s", r.Name, strings.Join(matches, ", "))
+		}
+	case !firing && wasFiring:
+		event.Type = eventRuleResolved
+		event.Targets = matches
+		event.Message = fmt.Sprintf("规则 %s 已恢复", r.Name)
+	case firing && r.Mode == ruleModeAny && len(added) > 0:
+		event.Type = eventRuleFiring
+		event.Targets = added
+		event.Message = fmt.Sprintf("规则 %s 新增满足条件的%s: %s", r.Name, ruleTargetName(r.Target), strings.Join(added, ", "))
+	default:
+		return Event{}, false
+	}
+	if r.Description != "" {
+		event.Message += "（" + r.Description + "）"
+	}
+	return event, true
+}
+
+func ruleTargetName(target string) string {
+	if target == ruleTargetInterface {
+		return "网卡"
+	}
+	return "服务"
+}
+
+// Status 返回全部规则的当前状态
+func (e *RuleEngine) Status() []RuleStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	statuses := make([]RuleStatus, 0, len(e.rules))
+	for _, r := range e.rules {
+		statuses = append(statuses, r.status())
+	}
+	return statuses
+}
+
+// 规则状态
+func rulesHandler(engine *RuleEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(engine.Status())
+	}
+}
@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuleEngineBaseline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), baselineFileName)
+	writeBaselineFile(t, path, "listeners:\n  - port: 22\n", time.Now())
+
+	events := NewEventEngine(AlertConfig{})
+	engine := NewRuleEngine([]RuleConfig{{Name: "unexpected", When: `baseline == "unexpected"`}}, NewBaseline(path), events)
+	if len(engine.rules) != 1 {
+		t.Fatalf("有效的规则 = %d, want 1", len(engine.rules))
+	}
+
+	ssh := Service{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "22", Name: "sshd"}
+	redis := Service{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "6379", Name: "redis-server"}
+
+	engine.Evaluate(&Snapshot{Time: time.Now(), Services: []Service{ssh}})
+	if status := engine.Status()[0]; status.Firing {
+		t.Fatalf("只有基线中的监听时不应触发: %+v", status)
+	}
+
+	snapshot := &Snapshot{Time: time.Now(), Services: []Service{ssh, redis}}
+	engine.Evaluate(snapshot)
+	status := engine.Status()[0]
+	if !status.Firing || len(status.Matches) != 1 || status.Matches[0] != describeListener(redis) {
+		t.Errorf("基线外的监听应该触发规则: %+v", status)
+	}
+	// 快照同时发给了其他订阅者，不能修改
+	if snapshot.Services[1].Baseline != "" {
+		t.Errorf("快照中的服务被修改: baseline = %q", snapshot.Services[1].Baseline)
+	}
+
+	recent := events.Events(1)
+	if len(recent) != 1 || recent[0].Type != eventRuleFiring || recent[0].Rule != "unexpected" {
+		t.Errorf("应该记录 %s 事件: %+v", eventRuleFiring, recent)
+	}
+}
@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// 数据文件格式版本。修改格式时递增版本号并在 dataMigrations 中添加对应的升级函数
+const dataSchemaVersion = 1
+
+// 数据文件版本高于程序支持的版本
+var errDataVersionTooNew = errors.New("数据文件版本高于当前程序支持的版本")
+
+// 数据文件的原始内容，按顶层字段保留未解析的JSON
+type rawStoreData map[string]json.RawMessage
+
+// 将数据从 From 版本升级到 From+1 版本
+type dataMigration struct {
+	From    int
+	Migrate func(rawStoreData) error
+}
+
+// 按版本顺序排列的升级函数
+var dataMigrations = []dataMigration{
+	{From: 0, Migrate: migrateDataV0},
+}
+
+// 版本0：没有version字段的旧格式。旧版加载逻辑把缺失的visible视为true，
+// 升级时显式补上，避免强类型解析后变成false
+func migrateDataV0(data rawStoreData) error {
+	raw, ok := data["column_configs"]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+
+	var entries []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return fmt.Errorf("column_configs: %v", err)
+	}
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		if _, ok := entry["visible"]; !ok {
+			entry["visible"] = json.RawMessage("true")
+		}
+	}
+
+	updated, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	data["column_configs"] = updated
+	return nil
+}
+
+// 解析数据文件内容：读取版本号、依次执行升级，再逐条解析和校验。
+// 单条记录的错误不会影响其他记录，全部通过 entryErrs 返回
+func decodeStoreData(content []byte) (data StoreData, entryErrs []error, err error) {
+	var raw rawStoreData
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return data, nil, err
+	}
+	if raw == nil {
+		return data, nil, errors.New("数据文件内容为空")
+	}
+
+	version, err := rawDataVersion(raw)
+	if err != nil {
+		return data, nil, err
+	}
+
+	for _, migration := range dataMigrations {
+		if migration.From < version {
+			continue
+		}
+		if err := migration.Migrate(raw); err != nil {
+			return data, nil, fmt.Errorf("从版本 %d 升级失败: %v", migration.From, err)
+		}
+		version = migration.From + 1
+	}
+	if version != dataSchemaVersion {
+		return data, nil, fmt.Errorf("缺少从版本 %d 升级的函数", version)
+	}
+
+	data.Version = dataSchemaVersion
+
+	var errs []error
+	data.ServiceNames, errs = decodeEntries(raw, "service_names", validateServiceNameMapping)
+	entryErrs = append(entryErrs, errs...)
+	data.InterfaceConfigs, errs = decodeEntries(raw, "interface_configs", validateInterfaceConfig)
+	entryErrs = append(entryErrs, errs...)
+	data.ColumnConfigs, errs = decodeEntries(raw, "column_configs", validateColumnConfig)
+	entryErrs = append(entryErrs, errs...)
+	data.URLPaths, errs = decodeEntries(raw, "url_paths", validateURLPathMapping)
+	entryErrs = append(entryErrs, errs...)
+
+	return data, entryErrs, nil
+}
+
+// 读取版本号，没有version字段的旧格式为版本0。版本高于程序支持的版本时返回errDataVersionTooNew
+func rawDataVersion(raw rawStoreData) (int, error) {
+	version := 0
+	if v, ok := raw["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return 0, fmt.Errorf("无效的版本号: %v", err)
+		}
+	}
+	if version > dataSchemaVersion {
+		return version, fmt.Errorf("%w: %d > %d", errDataVersionTooNew, version, dataSchemaVersion)
+	}
+	return version, nil
+}
+
+// 检查已有的数据文件是否由更新版本的程序写入。无法解析的文件不算，
+// 损坏的文件可以被覆盖（加载时已经从备份恢复）
+func checkDataFileVersion(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var raw rawStoreData
+	if json.Unmarshal(content, &raw) != nil {
+		return nil
+	}
+	if _, err := rawDataVersion(raw); errors.Is(err, errDataVersionTooNew) {
+		return err
+	}
+	return nil
+}
+
+// 逐条解析一个列表字段，跳过无法解析或校验失败的记录
+func decodeEntries[T any](raw rawStoreData, field string, validate func(T) error) ([]T, []error) {
+	section, ok := raw[field]
+	if !ok || string(section) == "null" {
+		return nil, nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(section, &items); err != nil {
+		return nil, []error{fmt.Errorf("%s: %v", field, err)}
+	}
+
+	var entries []T
+	var errs []error
+	for i, item := range items {
+		var entry T
+		if err := json.Unmarshal(item, &entry); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %v", field, i, err))
+			continue
+		}
+		if err := validate(entry); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %v", field, i, err))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, errs
+}
+
+func validateServiceNameMapping(m ServiceNameMapping) error {
+	if m.Service_id == "" {
+		return errors.New("service_id 为空")
+	}
+	return nil
+}
+
+func validateInterfaceConfig(c InterfaceConfig) error {
+	if c.Name == "" {
+		return errors.New("name 为空")
+	}
+	return nil
+}
+
+func validateColumnConfig(c ColumnConfig) error {
+	if c.Table == "" {
+		return errors.New("table 为空")
+	}
+	if c.Column == "" {
+		return errors.New("column 为空")
+	}
+	return nil
+}
+
+func validateURLPathMapping(m URLPathMapping) error {
+	if m.Service_id == "" {
+		return errors.New("service_id 为空")
+	}
+	if len(m.Path) == 0 || m.Path[0] != '/' {
+		return fmt.Errorf("path %q 必须以/开头", m.Path)
+	}
+	return nil
+}
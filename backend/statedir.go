@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// root运行时的默认状态目录（与systemd StateDirectory=port-monitor一致）
+const systemStateDir = "/var/lib/port-monitor"
+
+// 状态目录下的文件名
+const (
+	dataFileName    = "data.json"
+	logFileName     = "server.log"
+	historyDirName  = "history"
+	legacyDataFile  = "data.json" // 旧版本保存在工作目录下的数据文件
+	stateDirAppName = "port-monitor"
+)
+
+// 默认状态目录：systemd通过STATE_DIRECTORY传入的目录优先；root用户使用/var/lib/port-monitor；
+// 其他用户使用 $XDG_STATE_HOME/port-monitor，未设置时为 ~/.local/state/port-monitor
+func defaultStateDir() string {
+	if dir := os.Getenv("STATE_DIRECTORY"); dir != "" {
+		// 可能包含多个以冒号分隔的目录，取第一个
+		return filepath.SplitList(dir)[0]
+	}
+	if os.Geteuid() == 0 {
+		return systemStateDir
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return filepath.Join(dir, stateDirAppName)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "state", stateDirAppName)
+	}
+	// 无法确定用户目录时退回到工作目录
+	return "."
+}
+
+// 状态目录下指定文件的路径
+func statePath(name string) string {
+	return filepath.Join(config.StateDir, name)
+}
+
+// 创建状态目录，并把工作目录下旧版本的数据文件迁移过来
+func initStateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	migrateLegacyDataFile(dir)
+	return nil
+}
+
+// 状态目录中还没有数据文件而工作目录中有时，复制一份到状态目录。
+// 原文件保留不动，便于回退到旧版本
+func migrateLegacyDataFile(dir string) {
+	target := filepath.Join(dir, dataFileName)
+	if _, err := os.Stat(target); err == nil {
+		return
+	}
+
+	legacyAbs, err := filepath.Abs(legacyDataFile)
+	if err != nil {
+		return
+	}
+	targetAbs, err := filepath.Abs(target)
+	if err != nil || legacyAbs == targetAbs {
+		return
+	}
+
+	content, err := os.ReadFile(legacyAbs)
+	if err != nil || len(content) == 0 {
+		// 打包脚本会创建空的data.json，无需迁移
+		return
+	}
+
+	if err := writeFileAtomic(target, content, 0644); err != nil {
+		log.Printf("迁移旧数据文件 %s 失败: %v\n", legacyAbs, err)
+		return
+	}
+	log.Printf("已将旧数据文件 %s 迁移到 %s\n", legacyAbs, target)
+}
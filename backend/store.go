@@ -0,0 +1,290 @@
+package backend
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Store 保存用户配置的服务名称、URL路径、接口配置和列配置。
+// 所有方法都可以被多个HTTP处理器并发调用
+type Store struct {
+	mu               sync.RWMutex
+	serviceNames     map[string]string
+	urlPaths         map[string]string
+	interfaceConfigs map[string]bool
+	columnConfigs    map[string]map[string]bool
+
+	// 串行化文件写入，避免并发保存相互覆盖
+	saveMu sync.Mutex
+	path   string
+}
+
+// 持久化到数据文件以及通过API返回的数据
+type StoreData struct {
+	Version          int                  `json:"version"`
+	ServiceNames     []ServiceNameMapping `json:"service_names"`
+	InterfaceConfigs []InterfaceConfig    `json:"interface_configs"`
+	ColumnConfigs    []ColumnConfig       `json:"column_configs"`
+	URLPaths         []URLPathMapping     `json:"url_paths"`
+}
+
+// NewStore 创建使用指定数据文件的Store
+func NewStore(path string) *Store {
+	return &Store{
+		serviceNames:     make(map[string]string),
+		urlPaths:         make(map[string]string),
+		interfaceConfigs: make(map[string]bool),
+		columnConfigs:    make(map[string]map[string]bool),
+		path:             path,
+	}
+}
+
+// 服务名称
+
+func (s *Store) ServiceName(serviceID string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name, ok := s.serviceNames[serviceID]
+	return name, ok
+}
+
+func (s *Store) SetServiceName(serviceID, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.serviceNames[serviceID] = name
+}
+
+func (s *Store) DeleteServiceName(serviceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.serviceNames, serviceID)
+}
+
+// URL路径
+
+func (s *Store) URLPath(serviceID string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	path, ok := s.urlPaths[serviceID]
+	return path, ok
+}
+
+func (s *Store) SetURLPath(serviceID, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.urlPaths[serviceID] = path
+}
+
+func (s *Store) DeleteURLPath(serviceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.urlPaths, serviceID)
+}
+
+// 接口配置
+
+func (s *Store) InterfaceShowLinks(name string) (bool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	showLinks, ok := s.interfaceConfigs[name]
+	return showLinks, ok
+}
+
+func (s *Store) SetInterfaceShowLinks(name string, showLinks bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interfaceConfigs[name] = showLinks
+}
+
+func (s *Store) DeleteInterfaceConfig(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.interfaceConfigs, name)
+}
+
+// 列配置
+
+func (s *Store) ColumnVisible(table, column string) (bool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	visible, ok := s.columnConfigs[table][column]
+	return visible, ok
+}
+
+func (s *Store) SetColumnVisible(table, column string, visible bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.columnConfigs[table] == nil {
+		s.columnConfigs[table] = make(map[string]bool)
+	}
+	s.columnConfigs[table][column] = visible
+}
+
+func (s *Store) DeleteColumnConfig(table, column string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.columnConfigs[table], column)
+	if len(s.columnConfigs[table]) == 0 {
+		delete(s.columnConfigs, table)
+	}
+}
+
+// Snapshot 返回当前全部数据的副本，按键排序保证输出稳定
+func (s *Store) Snapshot() StoreData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data := StoreData{
+		Version:          dataSchemaVersion,
+		ServiceNames:     make([]ServiceNameMapping, 0, len(s.serviceNames)),
+		InterfaceConfigs: make([]InterfaceConfig, 0, len(s.interfaceConfigs)),
+		ColumnConfigs:    []ColumnConfig{},
+		URLPaths:         make([]URLPathMapping, 0, len(s.urlPaths)),
+	}
+
+	for serviceID, name := range s.serviceNames {
+		data.ServiceNames = append(data.ServiceNames, ServiceNameMapping{Service_id: serviceID, Name: name})
+	}
+	sort.Slice(data.ServiceNames, func(i, j int) bool {
+		return data.ServiceNames[i].Service_id < data.ServiceNames[j].Service_id
+	})
+
+	for name, showLinks := range s.interfaceConfigs {
+		data.InterfaceConfigs = append(data.InterfaceConfigs, InterfaceConfig{Name: name, ShowLinks: showLinks})
+	}
+	sort.Slice(data.InterfaceConfigs, func(i, j int) bool {
+		return data.InterfaceConfigs[i].Name < data.InterfaceConfigs[j].Name
+	})
+
+	for table, columns := range s.columnConfigs {
+		for column, visible := range columns {
+			data.ColumnConfigs = append(data.ColumnConfigs, ColumnConfig{Table: table, Column: column, Visible: visible})
+		}
+	}
+	sort.Slice(data.ColumnConfigs, func(i, j int) bool {
+		if data.ColumnConfigs[i].Table != data.ColumnConfigs[j].Table {
+			return data.ColumnConfigs[i].Table < data.ColumnConfigs[j].Table
+		}
+		return data.ColumnConfigs[i].Column < data.ColumnConfigs[j].Column
+	})
+
+	for serviceID, path := range s.urlPaths {
+		data.URLPaths = append(data.URLPaths, URLPathMapping{Service_id: serviceID, Path: path})
+	}
+	sort.Slice(data.URLPaths, func(i, j int) bool {
+		return data.URLPaths[i].Service_id < data.URLPaths[j].Service_id
+	})
+
+	return data
+}
+
+// 读取并解析数据文件
+func readStoreFile(path string) (StoreData, []error, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return StoreData{}, nil, err
+	}
+	return decodeStoreData(content)
+}
+
+// Load 从数据文件加载已保存的配置。主文件损坏时依次尝试较新的备份
+func (s *Store) Load() {
+	data, entryErrs, err := readStoreFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			log.Println("数据文件不存在，将创建新文件")
+		} else if errors.Is(err, errDataVersionTooNew) {
+			log.Printf("数据文件 %s 由更新版本的程序写入（%v），本次运行不会保存修改，以免覆盖\n", s.path, err)
+		} else {
+			log.Printf("读取数据文件失败: %v\n", err)
+		}
+
+		recovered := false
+		for i := 1; i <= dataBackupCount; i++ {
+			backup := backupPath(s.path, i)
+			backupData, backupErrs, backupErr := readStoreFile(backup)
+			if backupErr != nil {
+				if !os.IsNotExist(backupErr) {
+					log.Printf("读取备份文件 %s 失败: %v\n", backup, backupErr)
+				}
+				continue
+			}
+			log.Printf("从备份文件 %s 恢复数据\n", backup)
+			data, entryErrs = backupData, backupErrs
+			recovered = true
+			break
+		}
+		if !recovered {
+			return
+		}
+	}
+
+	for _, entryErr := range entryErrs {
+		log.Printf("忽略数据文件中的无效记录: %v\n", entryErr)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, mapping := range data.ServiceNames {
+		s.serviceNames[mapping.Service_id] = mapping.Name
+	}
+	for _, config := range data.InterfaceConfigs {
+		s.interfaceConfigs[config.Name] = config.ShowLinks
+	}
+	for _, config := range data.ColumnConfigs {
+		if s.columnConfigs[config.Table] == nil {
+			s.columnConfigs[config.Table] = make(map[string]bool)
+		}
+		s.columnConfigs[config.Table][config.Column] = config.Visible
+	}
+	for _, mapping := range data.URLPaths {
+		s.urlPaths[mapping.Service_id] = mapping.Path
+	}
+
+	log.Printf("加载了 %d 个服务名称映射, %d 个接口配置, %d 个表格的列配置, %d 个URL路径映射\n",
+		len(s.serviceNames), len(s.interfaceConfigs), len(s.columnConfigs), len(s.urlPaths))
+}
+
+// Save 将当前配置写入数据文件。写入前轮转备份，写入过程是原子的
+func (s *Store) Save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	// 不覆盖更新版本的程序写入的数据文件，也不把它轮转到备份中
+	if err := checkDataFileVersion(s.path); err != nil {
+		log.Printf("拒绝保存数据文件 %s: %v\n", s.path, err)
+		return err
+	}
+
+	data := s.Snapshot()
+
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		log.Printf("序列化数据失败: %v\n", err)
+		return err
+	}
+	content = append(content, '\n')
+
+	// 沿用已有文件的权限（打包脚本会将其设为可写）
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(s.path); err == nil {
+		perm = info.Mode().Perm()
+	}
+
+	if err := rotateBackups(s.path, dataBackupCount); err != nil {
+		log.Printf("轮转数据文件备份失败: %v\n", err)
+	}
+
+	if err := writeFileAtomic(s.path, content, perm); err != nil {
+		log.Printf("保存数据到文件失败: %v\n", err)
+		return err
+	}
+
+	log.Printf("成功保存数据到文件，服务名称: %d个, 接口配置: %d个\n", len(data.ServiceNames), len(data.InterfaceConfigs))
+	return nil
+}
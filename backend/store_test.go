@@ -0,0 +1,127 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// 用 go test -race 运行，检查Store的方法可以被并发调用
+func TestStoreConcurrentAccess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), dataFileName)
+	store := NewStore(path)
+
+	const workers = 8
+	const rounds = 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				id := fmt.Sprintf("0.0.0.0:%d:tcp", 8000+i)
+				table := fmt.Sprintf("table-%d", w)
+
+				store.SetServiceName(id, fmt.Sprintf("svc-%d-%d", w, i))
+				store.SetURLPath(id, "/"+table)
+				store.SetInterfaceShowLinks(table, i%2 == 0)
+				store.SetColumnVisible(table, "pid", i%2 == 0)
+				store.ServiceName(id)
+				store.URLPath(id)
+				store.InterfaceShowLinks(table)
+				store.ColumnVisible(table, "pid")
+
+				if i%5 == 0 {
+					store.Snapshot()
+				}
+				if i%10 == 0 {
+					if err := store.Save(); err != nil {
+						t.Errorf("Save: %v", err)
+					}
+				}
+				if i%3 == 0 {
+					store.DeleteServiceName(id)
+					store.DeleteURLPath(id)
+					store.DeleteInterfaceConfig(table)
+					store.DeleteColumnConfig(table, "pid")
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 最后一次保存的内容与内存中的数据一致
+	loaded := NewStore(path)
+	loaded.Load()
+	want, got := store.Snapshot(), loaded.Snapshot()
+	if len(got.ServiceNames) != len(want.ServiceNames) || len(got.URLPaths) != len(want.URLPaths) ||
+		len(got.InterfaceConfigs) != len(want.InterfaceConfigs) || len(got.ColumnConfigs) != len(want.ColumnConfigs) {
+		t.Errorf("重新加载的数据与保存的不一致: got %+v, want %+v", got, want)
+	}
+}
+
+func TestStoreSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), dataFileName)
+	store := NewStore(path)
+	store.SetServiceName("0.0.0.0:22:tcp", "ssh")
+	store.SetURLPath("0.0.0.0:80:tcp", "/admin")
+	store.SetInterfaceShowLinks("eth0", false)
+	store.SetColumnVisible("tcpv4", "pid", false)
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewStore(path)
+	loaded.Load()
+	if name, ok := loaded.ServiceName("0.0.0.0:22:tcp"); !ok || name != "ssh" {
+		t.Errorf("服务名称 = %q, %v", name, ok)
+	}
+	if path, ok := loaded.URLPath("0.0.0.0:80:tcp"); !ok || path != "/admin" {
+		t.Errorf("URL路径 = %q, %v", path, ok)
+	}
+	if show, ok := loaded.InterfaceShowLinks("eth0"); !ok || show {
+		t.Errorf("接口配置 = %v, %v", show, ok)
+	}
+	if visible, ok := loaded.ColumnVisible("tcpv4", "pid"); !ok || visible {
+		t.Errorf("列配置 = %v, %v", visible, ok)
+	}
+}
+
+func TestStoreRefusesToOverwriteNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), dataFileName)
+	newer := fmt.Sprintf(`{"version": %d, "service_names": [{"service_id": "0.0.0.0:22:tcp", "name": "ssh"}], "future_field": []}`, dataSchemaVersion+1)
+	if err := os.WriteFile(path, []byte(newer), 0644); err != nil {
+		t.Fatal(err)
+	}
+	older := `{"version": 1, "service_names": [{"service_id": "0.0.0.0:80:tcp", "name": "web"}]}`
+	if err := os.WriteFile(backupPath(path, 1), []byte(older), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewStore(path)
+	store.Load()
+	// 仍然可以从备份加载数据用于显示
+	if name, ok := store.ServiceName("0.0.0.0:80:tcp"); !ok || name != "web" {
+		t.Errorf("从备份加载的服务名称 = %q, %v", name, ok)
+	}
+
+	store.SetServiceName("0.0.0.0:443:tcp", "https")
+	if err := store.Save(); !errors.Is(err, errDataVersionTooNew) {
+		t.Fatalf("Save() = %v, want errDataVersionTooNew", err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || string(content) != newer {
+		t.Errorf("新版本的数据文件被修改: %s", content)
+	}
+	if content, _ := os.ReadFile(backupPath(path, 1)); string(content) != older {
+		t.Errorf("备份文件被轮转: %s", content)
+	}
+}
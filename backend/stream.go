@@ -0,0 +1,372 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStreamInterval  = 5 // 秒
+	streamHeartbeat        = 30 * time.Second
+	streamRetry            = 3000 // 断开后浏览器重新连接的等待时间（毫秒）
+	streamClientBufferSize = 16
+)
+
+// 推送给浏览器的事件类型
+const (
+	streamEventSnapshot   = "snapshot"   // 连接时发送的完整数据
+	streamEventServices   = "services"   // 服务的增量更新
+	streamEventInterfaces = "interfaces" // 网卡列表变化
+)
+
+// 配置文件中的实时推送配置
+type StreamConfig struct {
+	Interval int `yaml:"interval"` // 采样间隔（秒），0为默认值5，小于0表示不推送
+}
+
+// 推送的服务，Key在服务列表中唯一，用于浏览器端增量更新
+type StreamService struct {
+	Key string `json:"key"`
+	Service
+}
+
+// 连接时发送的完整数据
+type StreamSnapshot struct {
+	Version    int64           `json:"version"`
+	Time       time.Time       `json:"time"`
+	Services   []StreamService `json:"services"`
+	Interfaces []InterfaceInfo `json:"interfaces"`
+}
+
+// 服务的增量更新，Updated为内容发生变化的服务，Removed为消失的服务的Key
+type StreamUpdate struct {
+	Version int64           `json:"version"`
+	Time    time.Time       `json:"time"`
+	Added   []StreamService `json:"added"`
+	Updated []StreamService `json:"updated"`
+	Removed []string        `json:"removed"`
+}
+
+type streamMessage struct {
+	event string
+	id    int64
+	data  []byte
+}
+
+// 推送的服务及其序列化结果，用于判断内容是否变化
+type streamEntry struct {
+	service StreamService
+	data    []byte
+}
+
+// Stream 订阅采样器，缓存每次采样的服务列表和当前的网卡列表，
+// 与上一次比较后把变化通过SSE推送给浏览器
+type Stream struct {
+	enabled   bool
+	annotator *ServiceAnnotator
+	sampler   *Sampler
+
+	mu         sync.RWMutex
+	version    int64
+	updated    time.Time
+	services   []Service              // 最近一次采样的原始服务列表
+	entries    map[string]streamEntry // 已推送的服务（带标注）
+	keys       []string               // entries的顺序与采集结果一致
+	interfaces []InterfaceInfo
+	clients    map[chan streamMessage]struct{}
+}
+
+// NewStream 根据配置创建实时推送，服务列表来自采样器
+func NewStream(cfg StreamConfig, annotator *ServiceAnnotator, sampler *Sampler) *Stream {
+	return &Stream{
+		enabled:    streamInterval(cfg) > 0,
+		annotator:  annotator,
+		sampler:    sampler,
+		entries:    make(map[string]streamEntry),
+		interfaces: []InterfaceInfo{},
+		clients:    make(map[chan streamMessage]struct{}),
+	}
+}
+
+func streamInterval(cfg StreamConfig) time.Duration {
+	if cfg.Interval == 0 {
+		return defaultStreamInterval * time.Second
+	}
+	return time.Duration(cfg.Interval) * time.Second
+}
+
+// samplingInterval 返回采样器的间隔。历史和实时推送共用一个采样器，按两者中较短的间隔采样，
+// 历史只在服务列表变化时写入，采样更频繁不会增加历史记录
+func samplingInterval(history time.Duration, cfg StreamConfig) time.Duration {
+	stream := streamInterval(cfg)
+	if stream > 0 && (history <= 0 || stream < history) {
+		return stream
+	}
+	return history
+}
+
+// Start 订阅采样器，每次采样后更新缓存并推送变化。更新在后台进行，不阻塞启动，
+// 第一次采样完成之前浏览器收到的是空列表，之后通过增量更新补全
+func (s *Stream) Start() {
+	if !s.enabled {
+		log.Println("实时推送已禁用")
+		return
+	}
+
+	ch, _ := s.sampler.Subscribe()
+	go func() {
+		// 订阅之前可能已经完成了第一次采样
+		if snapshot := s.sampler.Latest(); snapshot != nil {
+			s.update(snapshot)
+		}
+		for snapshot := range ch {
+			s.update(snapshot)
+		}
+	}()
+}
+
+// Enabled 是否推送
+func (s *Stream) Enabled() bool {
+	return s.enabled
+}
+
+// Services 返回最近一次采样的服务列表的副本，尚未采样时返回nil
+func (s *Stream) Services() []Service {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.services == nil {
+		return nil
+	}
+	return append([]Service(nil), s.services...)
+}
+
+// 服务在列表中的唯一标识：serviceID已经区分网络命名空间，
+// 同一监听还可能被多个进程同时监听（SO_REUSEPORT）
+func streamKeys(services []Service) []string {
+	keys := make([]string, len(services))
+	seen := make(map[string]int)
+	for i, service := range services {
+		key := serviceID(service)
+		seen[key]++
+		if n := seen[key]; n > 1 {
+			key += "#" + strconv.Itoa(n)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+// 使用一次采样的结果更新缓存。快照同时发给了其他订阅者，只修改副本
+func (s *Stream) update(snapshot *Snapshot) {
+	services := append([]Service(nil), snapshot.Services...)
+	interfaces, err := getLocalInterfaces()
+	if err != nil {
+		log.Printf("获取网卡地址失败: %v\n", err)
+	}
+	if publicIP := s.annotator.exposure.cachedPublicIP(); publicIP != "" {
+		interfaces = append(interfaces, InterfaceInfo{Name: "公网", IP: publicIP})
+	}
+	if interfaces == nil {
+		interfaces = []InterfaceInfo{}
+	}
+
+	// 相同进程的多个套接字顺序不固定，排序后Key才稳定
+	sort.SliceStable(services, func(i, j int) bool {
+		if services[i].Netns != services[j].Netns {
+			return services[i].Netns < services[j].Netns
+		}
+		if a, b := serviceID(services[i]), serviceID(services[j]); a != b {
+			return a < b
+		}
+		return services[i].PID < services[j].PID
+	})
+
+	annotated := append([]Service(nil), services...)
+	s.annotator.Annotate(annotated)
+	keys := streamKeys(annotated)
+	entries := make(map[string]streamEntry, len(annotated))
+	for i, service := range annotated {
+		item := StreamService{Key: keys[i], Service: service}
+		data, err := json.Marshal(item)
+		if err != nil {
+			log.Printf("序列化服务 %s 失败: %v\n", keys[i], err)
+			continue
+		}
+		entries[keys[i]] = streamEntry{service: item, data: data}
+	}
+
+	now := snapshot.Time
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update := StreamUpdate{Time: now, Added: []StreamService{}, Updated: []StreamService{}, Removed: []string{}}
+	for _, key := range keys {
+		entry, ok := entries[key]
+		if !ok {
+			continue
+		}
+		previous, found := s.entries[key]
+		switch {
+		case !found:
+			update.Added = append(update.Added, entry.service)
+		case !bytes.Equal(previous.data, entry.data):
+			update.Updated = append(update.Updated, entry.service)
+		}
+	}
+	for _, key := range s.keys {
+		if _, ok := entries[key]; !ok {
+			update.Removed = append(update.Removed, key)
+		}
+	}
+	interfacesChanged := !reflect.DeepEqual(s.interfaces, interfaces)
+
+	s.services = services
+	s.entries = entries
+	s.keys = keys
+	s.interfaces = interfaces
+	s.updated = now
+
+	if len(update.Added) == 0 && len(update.Updated) == 0 && len(update.Removed) == 0 && !interfacesChanged {
+		return
+	}
+	s.version++
+	update.Version = s.version
+	if len(update.Added) > 0 || len(update.Updated) > 0 || len(update.Removed) > 0 {
+		s.broadcastLocked(streamEventServices, update)
+	}
+	if interfacesChanged {
+		s.broadcastLocked(streamEventInterfaces, struct {
+			Version    int64           `json:"version"`
+			Interfaces []InterfaceInfo `json:"interfaces"`
+		}{s.version, interfaces})
+	}
+}
+
+// 把消息发给所有浏览器。处理不过来的连接直接断开，
+// 浏览器重新连接后会收到完整数据，不会漏掉更新
+func (s *Stream) broadcastLocked(event string, payload interface{}) {
+	if len(s.clients) == 0 {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("序列化推送数据失败: %v\n", err)
+		return
+	}
+
+	message := streamMessage{event: event, id: s.version, data: data}
+	for ch := range s.clients {
+		select {
+		case ch <- message:
+		default:
+			delete(s.clients, ch)
+			close(ch)
+		}
+	}
+}
+
+// 注册一个浏览器连接，返回当前的完整数据。两者在同一把锁内完成，
+// 之后的每次变化都会进入返回的通道
+func (s *Stream) subscribe() (StreamSnapshot, chan streamMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := StreamSnapshot{
+		Version:    s.version,
+		Time:       s.updated,
+		Services:   make([]StreamService, 0, len(s.keys)),
+		Interfaces: s.interfaces,
+	}
+	for _, key := range s.keys {
+		if entry, ok := s.entries[key]; ok {
+			snapshot.Services = append(snapshot.Services, entry.service)
+		}
+	}
+
+	ch := make(chan streamMessage, streamClientBufferSize)
+	s.clients[ch] = struct{}{}
+	return snapshot, ch
+}
+
+func (s *Stream) unsubscribe(ch chan streamMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[ch]; ok {
+		delete(s.clients, ch)
+		close(ch)
+	}
+}
+
+func writeStreamMessage(w http.ResponseWriter, message streamMessage) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.id, message.event, message.data)
+	return err
+}
+
+// 实时推送（Server-Sent Events）：连接后先发送snapshot事件，
+// 之后服务变化时发送services事件，网卡变化时发送interfaces事件
+func streamHandler(stream *Stream) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !stream.Enabled() {
+			http.Error(w, "实时推送已禁用", http.StatusServiceUnavailable)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "不支持实时推送", http.StatusInternalServerError)
+			return
+		}
+
+		snapshot, ch := stream.subscribe()
+		defer stream.unsubscribe(ch)
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		// 禁止反向代理缓冲
+		w.Header().Set("X-Accel-Buffering", "no")
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+		if err := writeStreamMessage(w, streamMessage{event: streamEventSnapshot, id: snapshot.Version, data: data}); err != nil {
+			return
+		}
+		flusher.Flush()
+		log.Printf("浏览器 %s 订阅了实时推送\n", r.RemoteAddr)
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				log.Printf("浏览器 %s 断开了实时推送\n", r.RemoteAddr)
+				return
+			case message, ok := <-ch:
+				if !ok {
+					log.Printf("浏览器 %s 接收推送过慢，断开连接\n", r.RemoteAddr)
+					return
+				}
+				if err := writeStreamMessage(w, message); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-heartbeat.C:
+				// 注释行，防止代理因连接空闲而断开
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
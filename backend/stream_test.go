@@ -0,0 +1,161 @@
+package backend
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSamplingInterval(t *testing.T) {
+	tests := []struct {
+		history time.Duration
+		stream  int
+		want    time.Duration
+	}{
+		{60 * time.Second, 0, 5 * time.Second},
+		{60 * time.Second, 10, 10 * time.Second},
+		{3 * time.Second, 5, 3 * time.Second},
+		{60 * time.Second, -1, 60 * time.Second},
+		{-time.Second, 0, 5 * time.Second},
+		{-time.Second, -1, -time.Second},
+	}
+	for _, tt := range tests {
+		if got := samplingInterval(tt.history, StreamConfig{Interval: tt.stream}); got != tt.want {
+			t.Errorf("samplingInterval(%s, %d) = %s, want %s", tt.history, tt.stream, got, tt.want)
+		}
+	}
+}
+
+func testAnnotator(t *testing.T, sampler *Sampler) *ServiceAnnotator {
+	store := NewStore(filepath.Join(t.TempDir(), dataFileName))
+	fingerprinter := NewFingerprinter(&PortRegistry{entries: make(map[string]PortEntry)}, sampler)
+	return &ServiceAnnotator{
+		baseline:      NewBaseline(filepath.Join(t.TempDir(), "baseline.yaml")),
+		exposure:      NewExposureAnalyzer(),
+		prober:        NewProber(ProbeConfig{}, store, sampler),
+		inspector:     NewTLSInspector(TLSConfig{}, sampler),
+		fingerprinter: fingerprinter,
+		discovery:     NewHTTPDiscovery(DiscoveryConfig{}, store, sampler, fingerprinter),
+	}
+}
+
+func receiveStreamUpdate(t *testing.T, ch chan streamMessage) StreamUpdate {
+	t.Helper()
+	for {
+		select {
+		case message := <-ch:
+			if message.event != streamEventServices {
+				continue
+			}
+			var update StreamUpdate
+			if err := json.Unmarshal(message.data, &update); err != nil {
+				t.Fatal(err)
+			}
+			return update
+		case <-time.After(2 * time.Second):
+			t.Fatal("没有收到服务更新")
+		}
+	}
+}
+
+func TestStreamFollowsSampler(t *testing.T) {
+	sampler := NewSampler(0, nil)
+	stream := NewStream(StreamConfig{}, testAnnotator(t, sampler), sampler)
+
+	// 启动时不采集，第一次采样之前是空列表
+	stream.Start()
+	snapshot, ch := stream.subscribe()
+	defer stream.unsubscribe(ch)
+	if len(snapshot.Services) != 0 || stream.Services() != nil {
+		t.Fatalf("第一次采样之前 services = %v", snapshot.Services)
+	}
+
+	services := []Service{
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "80", PID: "20"},
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "22", PID: "10"},
+	}
+	sampler.publish(&Snapshot{Time: time.Now(), Services: services})
+	update := receiveStreamUpdate(t, ch)
+	if len(update.Added) != 2 || update.Added[0].Key != "0.0.0.0:22:tcp" {
+		t.Errorf("第一次更新 added = %+v", update.Added)
+	}
+	// 快照同时发给了其他订阅者，不能被修改
+	if services[0].LocalPort != "80" || services[0].Exposure != nil {
+		t.Error("采样器的快照被修改")
+	}
+
+	sampler.publish(&Snapshot{Time: time.Now(), Services: []Service{
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "22", PID: "11"},
+	}})
+	update = receiveStreamUpdate(t, ch)
+	if len(update.Updated) != 1 || update.Updated[0].PID != "11" {
+		t.Errorf("updated = %+v, want 22端口换了进程", update.Updated)
+	}
+	if len(update.Removed) != 1 || update.Removed[0] != "0.0.0.0:80:tcp" {
+		t.Errorf("removed = %v, want [0.0.0.0:80:tcp]", update.Removed)
+	}
+	if got := stream.Services(); len(got) != 1 || got[0].LocalPort != "22" {
+		t.Errorf("Services() = %+v", got)
+	}
+}
+
+func TestStreamStartsFromLatestSample(t *testing.T) {
+	sampler := NewSampler(0, nil)
+	sampler.publish(&Snapshot{Time: time.Now(), Services: []Service{{Protocol: "udp", LocalAddr: "0.0.0.0", LocalPort: "53"}}})
+
+	// 订阅之前已经完成的采样也会被使用
+	stream := NewStream(StreamConfig{}, testAnnotator(t, sampler), sampler)
+	stream.Start()
+	deadline := time.Now().Add(2 * time.Second)
+	for stream.Services() == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("没有使用已有的采样结果")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStreamDisabled(t *testing.T) {
+	sampler := NewSampler(0, nil)
+	stream := NewStream(StreamConfig{Interval: -1}, testAnnotator(t, sampler), sampler)
+	stream.Start()
+	if stream.Enabled() {
+		t.Error("interval小于0时不应推送")
+	}
+}
+
+func TestStreamKeys(t *testing.T) {
+	services := []Service{
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "80", Netns: "host"},
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "80", Netns: "app"},
+		{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: "80", Netns: "app"},
+	}
+	keys := streamKeys(services)
+	want := []string{"0.0.0.0:80:tcp", "app|0.0.0.0:80:tcp", "app|0.0.0.0:80:tcp#2"}
+	for i := range want {
+		if keys[i] != want[i] {
+			t.Errorf("keys[%d] = %q, want %q", i, keys[i], want[i])
+		}
+	}
+}
+
+func TestSamplerEffectiveInterval(t *testing.T) {
+	if got := NewSampler(10*time.Second, nil).effectiveInterval(); got != 10*time.Second {
+		t.Errorf("effectiveInterval() = %s, want 10s", got)
+	}
+
+	// 历史和实时推送都关闭时，没有订阅者才不采样
+	sampler := NewSampler(-time.Second, nil)
+	if got := sampler.effectiveInterval(); got != 0 {
+		t.Errorf("没有订阅者时 effectiveInterval() = %s, want 0", got)
+	}
+	_, unsubscribe := sampler.Subscribe()
+	if got := sampler.effectiveInterval(); got != defaultHistoryInterval*time.Second {
+		t.Errorf("有订阅者时 effectiveInterval() = %s, want %ds", got, defaultHistoryInterval)
+	}
+	unsubscribe()
+	if got := sampler.effectiveInterval(); got != 0 {
+		t.Errorf("取消订阅后 effectiveInterval() = %s, want 0", got)
+	}
+}
@@ -0,0 +1,361 @@
+package backend
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 证书状态
+const (
+	tlsStatusOK      = "ok"
+	tlsStatusWarning = "warning" // 即将过期或证书链无效
+	tlsStatusExpired = "expired"
+)
+
+const (
+	defaultTLSInterval = 600 // 秒
+	defaultTLSWarnDays = 30
+	defaultTLSTimeout  = 3 * time.Second
+)
+
+// 配置文件中的TLS检查配置
+type TLSConfig struct {
+	Interval    int               `yaml:"interval"`  // 秒，0为默认值600，小于0表示不检查
+	WarnDays    int               `yaml:"warn_days"` // 证书过期前多少天开始警告，默认30
+	Timeout     string            `yaml:"timeout"`
+	Include     []ListenerMatcher `yaml:"include"` // 只检查匹配的监听，为空表示全部TCP监听
+	Exclude     []ListenerMatcher `yaml:"exclude"`
+	ServerNames []TLSServerName   `yaml:"server_names"` // 按名称（SNI）选择证书的服务需要指定，否则检查到的是默认证书
+}
+
+// 匹配的监听握手时使用的服务器名称
+type TLSServerName struct {
+	ListenerMatcher `yaml:",inline"`
+	ServerName      string `yaml:"server_name"`
+}
+
+// 监听的TLS证书信息
+type TLSInfo struct {
+	ServiceID  string    `json:"service_id"`
+	Target     string    `json:"target"`
+	ServerName string    `json:"server_name,omitempty"` // 握手时发送的SNI
+	Version    string    `json:"version"`               // 协商的协议版本，例如 TLS 1.3
+	Subject    string    `json:"subject"`
+	SANs       []string  `json:"sans"`
+	Issuer     string    `json:"issuer"`
+	NotBefore  time.Time `json:"not_before"`
+	NotAfter   time.Time `json:"not_after"`
+	DaysLeft   int       `json:"days_left"`
+	SelfSigned bool      `json:"self_signed"`
+	ChainValid bool      `json:"chain_valid"` // 证书链能否验证到系统信任的根证书
+	ChainError string    `json:"chain_error,omitempty"`
+	Status     string    `json:"status"` // ok, warning, expired
+	Warnings   []string  `json:"warnings"`
+	Time       time.Time `json:"time"`
+}
+
+// TLSInspector 定期与TCP监听进行TLS握手，记录证书信息
+type TLSInspector struct {
+	interval time.Duration
+	warnDays int
+	timeout  time.Duration
+	include  []ListenerMatcher
+	exclude  []ListenerMatcher
+	names    []TLSServerName
+	sampler  *Sampler
+
+	mu      sync.RWMutex
+	results map[string]*TLSInfo // 只包含支持TLS的监听
+}
+
+// NewTLSInspector 根据配置创建TLS检查器
+func NewTLSInspector(cfg TLSConfig, sampler *Sampler) *TLSInspector {
+	inspector := &TLSInspector{
+		interval: time.Duration(cfg.Interval) * time.Second,
+		warnDays: cfg.WarnDays,
+		timeout:  defaultTLSTimeout,
+		include:  validMatchers(cfg.Include),
+		exclude:  validMatchers(cfg.Exclude),
+		sampler:  sampler,
+		results:  make(map[string]*TLSInfo),
+	}
+	if cfg.Interval == 0 {
+		inspector.interval = defaultTLSInterval * time.Second
+	}
+	if inspector.warnDays <= 0 {
+		inspector.warnDays = defaultTLSWarnDays
+	}
+	if cfg.Timeout != "" {
+		if d, err := time.ParseDuration(cfg.Timeout); err == nil && d > 0 {
+			inspector.timeout = d
+		} else {
+			log.Printf("TLS检查的timeout无效，使用默认值 %s: %s\n", defaultTLSTimeout, cfg.Timeout)
+		}
+	}
+	for _, name := range cfg.ServerNames {
+		if name.ServerName == "" {
+			log.Printf("忽略TLS检查的服务器名称 %s: 缺少server_name\n", name.ListenerMatcher)
+			continue
+		}
+		if err := validateListenerMatcher(name.ListenerMatcher); err != nil {
+			log.Printf("忽略TLS检查的服务器名称 %s: %v\n", name.ServerName, err)
+			continue
+		}
+		inspector.names = append(inspector.names, name)
+	}
+	return inspector
+}
+
+// Start 在后台开始定期检查
+func (t *TLSInspector) Start() {
+	if t.interval <= 0 {
+		log.Println("TLS证书检查已禁用")
+		return
+	}
+
+	go func() {
+		t.inspectAll()
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			t.inspectAll()
+		}
+	}()
+}
+
+func (t *TLSInspector) selected(service Service) bool {
+	if service.Protocol != "tcp" || (service.Netns != "" && service.Netns != "host") {
+		return false
+	}
+	for _, matcher := range t.exclude {
+		if matcher.matches(service) {
+			return false
+		}
+	}
+	if len(t.include) == 0 {
+		return true
+	}
+	for _, matcher := range t.include {
+		if matcher.matches(service) {
+			return true
+		}
+	}
+	return false
+}
+
+// 监听握手时使用的服务器名称，使用第一个匹配的配置，没有匹配时不发送SNI
+func (t *TLSInspector) serverName(service Service) string {
+	for _, name := range t.names {
+		if name.matches(service) {
+			return name.ServerName
+		}
+	}
+	return ""
+}
+
+func (t *TLSInspector) inspectAll() {
+	snapshot, err := currentSnapshot(t.sampler)
+	if err != nil {
+		log.Printf("获取服务列表失败，跳过本次TLS检查: %v\n", err)
+		return
+	}
+
+	var services []Service
+	for _, service := range snapshot.Services {
+		if t.selected(service) {
+			services = append(services, service)
+		}
+	}
+
+	infos := make([]*TLSInfo, len(services))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for i, service := range services {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, service Service) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			target := net.JoinHostPort(probeHost(service.LocalAddr), service.LocalPort)
+			if info, err := t.Inspect(target, t.serverName(service)); err == nil {
+				info.ServiceID = serviceID(service)
+				infos[i] = info
+			}
+		}(i, service)
+	}
+	wg.Wait()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	results := make(map[string]*TLSInfo)
+	for _, info := range infos {
+		if info == nil {
+			continue
+		}
+		// 只在状态变化时记录，避免每次检查重复输出
+		if previous := t.results[info.ServiceID]; (previous == nil || previous.Status != info.Status) && info.Status != tlsStatusOK {
+			log.Printf("TLS证书 %s (%s): %s\n", info.Target, info.Subject, strings.Join(info.Warnings, "; "))
+		}
+		results[info.ServiceID] = info
+	}
+	t.results = results
+}
+
+// Inspect 与指定地址进行TLS握手并分析证书，不支持TLS的监听返回错误。
+// serverName 不为空时作为SNI发送，并检查证书是否与该名称匹配
+func (t *TLSInspector) Inspect(target, serverName string) (*TLSInfo, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: t.timeout},
+		// 证书链在握手后单独验证，以便记录无效证书的信息
+		Config: &tls.Config{InsecureSkipVerify: true, ServerName: serverName},
+	}
+	conn, err := dialer.Dial("tcp", target)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	return analyzeCertificates(target, serverName, state, t.warnDays, time.Now()), nil
+}
+
+func analyzeCertificates(target, serverName string, state tls.ConnectionState, warnDays int, now time.Time) *TLSInfo {
+	info := &TLSInfo{
+		Target:     target,
+		ServerName: serverName,
+		Version:    tls.VersionName(state.Version),
+		SANs:       []string{},
+		Warnings:   []string{},
+		Status:     tlsStatusOK,
+		Time:       now,
+	}
+	if len(state.PeerCertificates) == 0 {
+		info.Status = tlsStatusWarning
+		info.Warnings = append(info.Warnings, "服务端没有提供证书")
+		return info
+	}
+
+	leaf := state.PeerCertificates[0]
+	info.Subject = leaf.Subject.String()
+	info.Issuer = leaf.Issuer.String()
+	info.NotBefore = leaf.NotBefore
+	info.NotAfter = leaf.NotAfter
+	info.DaysLeft = int(leaf.NotAfter.Sub(now).Hours() / 24)
+	info.SANs = append(info.SANs, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	info.SANs = append(info.SANs, leaf.EmailAddresses...)
+	for _, uri := range leaf.URIs {
+		info.SANs = append(info.SANs, uri.String())
+	}
+	info.SelfSigned = leaf.CheckSignatureFrom(leaf) == nil
+
+	// 没有配置服务器名称时只验证证书链，不检查主机名：探测使用的是IP地址
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{DNSName: serverName, Intermediates: intermediates, CurrentTime: now})
+	info.ChainValid = err == nil
+	if err != nil {
+		info.ChainError = err.Error()
+	}
+
+	switch {
+	case now.After(leaf.NotAfter):
+		info.Status = tlsStatusExpired
+		info.Warnings = append(info.Warnings, "证书已于 "+leaf.NotAfter.Format("2006-01-02")+" 过期")
+	case now.Before(leaf.NotBefore):
+		info.Status = tlsStatusWarning
+		info.Warnings = append(info.Warnings, "证书在 "+leaf.NotBefore.Format("2006-01-02")+" 之后才生效")
+	case info.DaysLeft < warnDays:
+		info.Status = tlsStatusWarning
+		info.Warnings = append(info.Warnings, "证书将在 "+leaf.NotAfter.Format("2006-01-02")+" 过期")
+	}
+	if !info.ChainValid && info.Status != tlsStatusExpired {
+		info.Status = tlsStatusWarning
+		if info.SelfSigned {
+			info.Warnings = append(info.Warnings, "自签名证书")
+		} else {
+			info.Warnings = append(info.Warnings, "证书链无效: "+info.ChainError)
+		}
+	}
+	if state.Version < tls.VersionTLS12 {
+		info.Status = maxTLSStatus(info.Status, tlsStatusWarning)
+		info.Warnings = append(info.Warnings, "协议版本过低: "+info.Version)
+	}
+	return info
+}
+
+func maxTLSStatus(a, b string) string {
+	rank := map[string]int{tlsStatusOK: 0, tlsStatusWarning: 1, tlsStatusExpired: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// Annotate 把证书信息标注到支持TLS的服务上
+func (t *TLSInspector) Annotate(services []Service) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for i := range services {
+		if services[i].Netns != "" && services[i].Netns != "host" {
+			continue
+		}
+		if info, ok := t.results[serviceID(services[i])]; ok {
+			copied := *info
+			services[i].TLS = &copied
+		}
+	}
+}
+
+// Results 返回全部支持TLS的监听，最先过期的在前
+func (t *TLSInspector) Results() []TLSInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	results := make([]TLSInfo, 0, len(t.results))
+	for _, info := range t.results {
+		results = append(results, *info)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].NotAfter.Equal(results[j].NotAfter) {
+			return results[i].NotAfter.Before(results[j].NotAfter)
+		}
+		return results[i].ServiceID < results[j].ServiceID
+	})
+	return results
+}
+
+// TLS证书检查结果，?status=warning 只返回需要关注的证书（即将过期、已过期或无效）
+func tlsHandler(inspector *TLSInspector) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := inspector.Results()
+		if r.URL.Query().Get("status") == tlsStatusWarning {
+			filtered := []TLSInfo{}
+			for _, info := range results {
+				if info.Status != tlsStatusOK {
+					filtered = append(filtered, info)
+				}
+			}
+			results = filtered
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			WarnDays     int       `json:"warn_days"`
+			Certificates []TLSInfo `json:"certificates"`
+		}{inspector.warnDays, results})
+	}
+}
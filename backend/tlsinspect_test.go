@@ -0,0 +1,244 @@
+package backend
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var tlsTestNow = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// 生成测试证书，parent为nil时生成自签名证书
+func testCertificate(t *testing.T, name string, notBefore, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func hasWarning(info *TLSInfo, text string) bool {
+	for _, warning := range info.Warnings {
+		if strings.Contains(warning, text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAnalyzeCertificates(t *testing.T) {
+	ca, caKey := testCertificate(t, "test-ca", tlsTestNow.AddDate(-1, 0, 0), tlsTestNow.AddDate(5, 0, 0), nil, nil)
+	leaf := func(notBefore, notAfter time.Time) *x509.Certificate {
+		cert, _ := testCertificate(t, "db.internal", notBefore, notAfter, ca, caKey)
+		return cert
+	}
+	selfSigned, _ := testCertificate(t, "self.internal", tlsTestNow.AddDate(0, -1, 0), tlsTestNow.AddDate(1, 0, 0), nil, nil)
+	expiredSelfSigned, _ := testCertificate(t, "old.internal", tlsTestNow.AddDate(-2, 0, 0), tlsTestNow.AddDate(0, 0, -3), nil, nil)
+
+	tests := []struct {
+		name       string
+		certs      []*x509.Certificate
+		version    uint16
+		status     string
+		daysLeft   int
+		selfSigned bool
+		warnings   []string
+	}{
+		{
+			name:     "已过期",
+			certs:    []*x509.Certificate{leaf(tlsTestNow.AddDate(-1, 0, 0), tlsTestNow.AddDate(0, 0, -1)), ca},
+			version:  tls.VersionTLS13,
+			status:   tlsStatusExpired,
+			daysLeft: -1,
+			warnings: []string{"证书已于 2026-05-31 过期"},
+		},
+		{
+			name:     "即将过期",
+			certs:    []*x509.Certificate{leaf(tlsTestNow.AddDate(0, -11, 0), tlsTestNow.AddDate(0, 0, 10)), ca},
+			version:  tls.VersionTLS13,
+			status:   tlsStatusWarning,
+			daysLeft: 10,
+			warnings: []string{"证书将在 2026-06-11 过期", "证书链无效"},
+		},
+		{
+			name:     "尚未生效",
+			certs:    []*x509.Certificate{leaf(tlsTestNow.AddDate(0, 0, 1), tlsTestNow.AddDate(1, 0, 0)), ca},
+			version:  tls.VersionTLS12,
+			status:   tlsStatusWarning,
+			daysLeft: 365,
+			warnings: []string{"证书在 2026-06-02 之后才生效"},
+		},
+		{
+			name:       "自签名",
+			certs:      []*x509.Certificate{selfSigned},
+			version:    tls.VersionTLS13,
+			status:     tlsStatusWarning,
+			daysLeft:   365,
+			selfSigned: true,
+			warnings:   []string{"自签名证书"},
+		},
+		{
+			name:       "已过期的自签名证书",
+			certs:      []*x509.Certificate{expiredSelfSigned},
+			version:    tls.VersionTLS12,
+			status:     tlsStatusExpired,
+			daysLeft:   -3,
+			selfSigned: true,
+			warnings:   []string{"过期"},
+		},
+		{
+			name:       "协议版本过低",
+			certs:      []*x509.Certificate{selfSigned},
+			version:    tls.VersionTLS10,
+			status:     tlsStatusWarning,
+			daysLeft:   365,
+			selfSigned: true,
+			warnings:   []string{"自签名证书", "协议版本过低: TLS 1.0"},
+		},
+	}
+	for _, tt := range tests {
+		state := tls.ConnectionState{Version: tt.version, PeerCertificates: tt.certs}
+		info := analyzeCertificates("127.0.0.1:5432", "", state, defaultTLSWarnDays, tlsTestNow)
+		if info.Status != tt.status || info.DaysLeft != tt.daysLeft || info.SelfSigned != tt.selfSigned {
+			t.Errorf("%s: status=%s days_left=%d self_signed=%v, want %s/%d/%v",
+				tt.name, info.Status, info.DaysLeft, info.SelfSigned, tt.status, tt.daysLeft, tt.selfSigned)
+		}
+		for _, warning := range tt.warnings {
+			if !hasWarning(info, warning) {
+				t.Errorf("%s: warnings = %q, want 包含 %q", tt.name, info.Warnings, warning)
+			}
+		}
+		if info.ChainValid {
+			t.Errorf("%s: 测试CA不受系统信任，证书链不应有效", tt.name)
+		}
+	}
+
+	// 已过期的自签名证书只提示过期
+	info := analyzeCertificates("127.0.0.1:5432", "", tls.ConnectionState{Version: tls.VersionTLS13, PeerCertificates: []*x509.Certificate{expiredSelfSigned}}, defaultTLSWarnDays, tlsTestNow)
+	if hasWarning(info, "自签名证书") {
+		t.Errorf("已过期的证书不应再提示自签名: %q", info.Warnings)
+	}
+
+	info = analyzeCertificates("127.0.0.1:5432", "", tls.ConnectionState{Version: tls.VersionTLS13}, defaultTLSWarnDays, tlsTestNow)
+	if info.Status != tlsStatusWarning || !hasWarning(info, "没有提供证书") {
+		t.Errorf("没有证书: status=%s warnings=%q", info.Status, info.Warnings)
+	}
+}
+
+func TestTLSInspectorInspect(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	inspector := NewTLSInspector(TLSConfig{}, nil)
+	target := server.Listener.Addr().String()
+	info, err := inspector.Inspect(target, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Target != target || info.Version != "TLS 1.3" {
+		t.Errorf("target=%s version=%s, want %s/TLS 1.3", info.Target, info.Version, target)
+	}
+	sans := strings.Join(info.SANs, ",")
+	if !strings.Contains(sans, "example.com") || !strings.Contains(sans, "127.0.0.1") {
+		t.Errorf("SANs = %v", info.SANs)
+	}
+	// httptest使用的是自签名证书
+	if !info.SelfSigned || info.ChainValid || info.Status != tlsStatusWarning || !hasWarning(info, "自签名证书") {
+		t.Errorf("self_signed=%v chain_valid=%v status=%s warnings=%q", info.SelfSigned, info.ChainValid, info.Status, info.Warnings)
+	}
+}
+
+func TestTLSInspectorInspectPlainTCP(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	inspector := NewTLSInspector(TLSConfig{Timeout: "1s"}, nil)
+	if _, err := inspector.Inspect(server.Listener.Addr().String(), ""); err == nil {
+		t.Error("不支持TLS的监听应该返回错误")
+	}
+}
+
+func TestTLSInspectorServerName(t *testing.T) {
+	namedCert, namedKey := testCertificate(t, "db.internal", tlsTestNow.AddDate(-1, 0, 0), tlsTestNow.AddDate(50, 0, 0), nil, nil)
+
+	// 按SNI选择证书的服务，没有SNI时使用httptest的默认证书
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	server.TLS = &tls.Config{GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if hello.ServerName == "db.internal" {
+			return &tls.Certificate{Certificate: [][]byte{namedCert.Raw}, PrivateKey: namedKey}, nil
+		}
+		return nil, nil
+	}}
+	server.StartTLS()
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	inspector := NewTLSInspector(TLSConfig{ServerNames: []TLSServerName{
+		{ListenerMatcher: ListenerMatcher{Port: 1}},                   // 缺少server_name，被忽略
+		{ListenerMatcher: ListenerMatcher{Port: -1}, ServerName: "x"}, // 无效的端口，被忽略
+		{ListenerMatcher: ListenerMatcher{Protocol: "tcp", Address: "127.0.0.1"}, ServerName: "db.internal"},
+	}}, nil)
+	if len(inspector.names) != 1 {
+		t.Fatalf("有效的服务器名称 = %d, want 1", len(inspector.names))
+	}
+
+	service := Service{Protocol: "tcp", LocalAddr: "127.0.0.1", LocalPort: port}
+	serverName := inspector.serverName(service)
+	if serverName != "db.internal" {
+		t.Fatalf("serverName() = %q, want db.internal", serverName)
+	}
+	if name := inspector.serverName(Service{Protocol: "tcp", LocalAddr: "0.0.0.0", LocalPort: port}); name != "" {
+		t.Errorf("不匹配的监听 serverName() = %q, want 空", name)
+	}
+
+	info, err := inspector.Inspect(server.Listener.Addr().String(), serverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ServerName != "db.internal" || info.Subject != "CN=db.internal" {
+		t.Errorf("server_name=%s subject=%s, want db.internal 的证书", info.ServerName, info.Subject)
+	}
+
+	info, err = inspector.Inspect(server.Listener.Addr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ServerName != "" || info.Subject == "CN=db.internal" {
+		t.Errorf("不发送SNI时 subject=%s, want 默认证书", info.Subject)
+	}
+}
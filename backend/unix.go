@@ -0,0 +1,139 @@
+package backend
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// /proc/net/unix 中的标志位和类型（对应内核 include/linux/net.h）
+const (
+	unixFlagAcceptCon = 0x10000 // __SO_ACCEPTCON，套接字处于监听状态
+
+	unixTypeStream    = 1
+	unixTypeDgram     = 2
+	unixTypeSeqPacket = 5
+)
+
+var unixTypeNames = map[int]string{
+	unixTypeStream:    "stream",
+	unixTypeDgram:     "dgram",
+	unixTypeSeqPacket: "seqpacket",
+}
+
+// socket_state 到显示名称的映射
+var unixStateNames = map[int]string{
+	1: "Unconnected",
+	2: "Connecting",
+	3: "Connected",
+	4: "Disconnecting",
+}
+
+// Unix域套接字
+type UnixSocket struct {
+	Path    string       `json:"path"` // 抽象套接字以@开头
+	Type    string       `json:"type"`
+	State   string       `json:"state"`
+	Inode   uint64       `json:"inode"`
+	Name    string       `json:"name"` // 进程名称
+	PID     string       `json:"pid"`
+	Process *ProcessInfo `json:"process,omitempty"`
+}
+
+// 解析 /proc/net/unix
+func parseProcNetUnix(r io.Reader) ([]UnixSocket, error) {
+	var sockets []UnixSocket
+	scanner := bufio.NewScanner(r)
+
+	// 跳过表头
+	if !scanner.Scan() {
+		return sockets, scanner.Err()
+	}
+
+	for scanner.Scan() {
+		// Num RefCount Protocol Flags Type St Inode [Path]
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 7 {
+			continue
+		}
+
+		flags, _ := strconv.ParseUint(fields[3], 16, 32)
+		sockType, _ := strconv.ParseUint(fields[4], 16, 16)
+		state, _ := strconv.ParseUint(fields[5], 16, 8)
+		inode, _ := strconv.ParseUint(fields[6], 10, 64)
+
+		socket := UnixSocket{
+			Type:  unixTypeNames[int(sockType)],
+			State: unixStateNames[int(state)],
+			Inode: inode,
+		}
+		if socket.Type == "" {
+			socket.Type = strconv.FormatUint(sockType, 10)
+		}
+		if flags&unixFlagAcceptCon != 0 {
+			socket.State = getServiceState("LISTEN")
+		}
+		if len(fields) > 7 {
+			socket.Path = strings.Join(fields[7:], " ")
+		}
+
+		sockets = append(sockets, socket)
+	}
+
+	return sockets, scanner.Err()
+}
+
+// 获取Unix域套接字。默认只返回监听中的套接字和已绑定路径的数据报套接字
+func getUnixSockets(all bool) ([]UnixSocket, error) {
+	file, err := os.Open(filepath.Join(procRoot, "net", "unix"))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sockets, err := parseProcNetUnix(file)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := newProcessResolver()
+	result := make([]UnixSocket, 0, len(sockets))
+	for _, socket := range sockets {
+		listening := socket.State == getServiceState("LISTEN")
+		boundDgram := socket.Type == unixTypeNames[unixTypeDgram] && socket.Path != "" && socket.State == unixStateNames[1]
+		if !all && !listening && !boundDgram {
+			continue
+		}
+
+		socket.Name = "N/A"
+		socket.PID = resolver.Owners(socket.Inode)
+		socket.Process = resolver.Resolve(socket.Inode)
+		if socket.Process != nil && socket.Process.Comm != "" {
+			socket.Name = socket.Process.Comm
+		}
+		result = append(result, socket)
+	}
+
+	return result, nil
+}
+
+// Unix域套接字列表，?all=1 返回包括已连接在内的全部套接字
+func unixSocketsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("接收到获取Unix套接字列表的请求")
+	sockets, err := getUnixSockets(r.URL.Query().Get("all") == "1")
+	if err != nil {
+		log.Printf("获取Unix套接字失败: %v\n", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(sockets)
+	log.Printf("成功返回 %d 个Unix套接字\n", len(sockets))
+}
@@ -84,6 +84,7 @@ Port Monitor 是一个轻量级的网络端口监控工具，用于实时监控
 mkdir -p %{buildroot}/opt/%{name}
 mkdir -p %{buildroot}/opt/%{name}/frontend/static
 mkdir -p %{buildroot}/usr/bin
+mkdir -p %{buildroot}/var/lib/%{name}
 
 # 复制预编译的二进制文件
 install -m 755 /source/build/port-monitor %{buildroot}/usr/bin/port-monitor
@@ -94,10 +95,6 @@ cp -r /source/build/frontend/static/* %{buildroot}/opt/%{name}/frontend/static/
 # 复制配置文件
 cp /source/build/config.yaml %{buildroot}/opt/%{name}/
 
-# 创建日志和数据文件
-touch %{buildroot}/opt/%{name}/server.log
-touch %{buildroot}/opt/%{name}/data.json
-
 # 创建systemd服务文件
 mkdir -p %{buildroot}/usr/lib/systemd/system
 cat > %{buildroot}/usr/lib/systemd/system/port-monitor.service << 'SERVICEEOF'
@@ -109,6 +106,7 @@ After=network.target
 Type=simple
 User=root
 WorkingDirectory=/opt/port-monitor
+StateDirectory=port-monitor
 ExecStart=/usr/bin/port-monitor
 Restart=always
 RestartSec=3
@@ -117,12 +115,18 @@ RestartSec=3
 WantedBy=multi-user.target
 SERVICEEOF
 
+%pre
+# 旧版本的数据文件保存在/opt/port-monitor下，升级时迁移到状态目录
+if [ -s /opt/port-monitor/data.json ] && [ ! -e /var/lib/port-monitor/data.json ]; then
+    mkdir -p /var/lib/port-monitor
+    cp -p /opt/port-monitor/data.json /var/lib/port-monitor/data.json
+fi
+
 %files
 /usr/bin/port-monitor
 /opt/port-monitor/frontend/static/*
 /opt/port-monitor/config.yaml
-/opt/port-monitor/server.log
-/opt/port-monitor/data.json
+%dir /var/lib/port-monitor
 /usr/lib/systemd/system/port-monitor.service
 
 %changelog
@@ -151,6 +155,7 @@ build_deb() {
     mkdir -p "${DEB_BUILD_DIR}/DEBIAN"
     mkdir -p "${DEB_BUILD_DIR}/opt/${PROJECT_NAME}"
     mkdir -p "${DEB_BUILD_DIR}/usr/bin"
+    mkdir -p "${DEB_BUILD_DIR}/var/lib/${PROJECT_NAME}"
     mkdir -p "${DEB_BUILD_DIR}/etc/systemd/system"
 
     # 复制文件
@@ -158,10 +163,6 @@ build_deb() {
     cp -r "${BUILD_DIR}/frontend" "${DEB_BUILD_DIR}/opt/${PROJECT_NAME}/"
     cp "${BUILD_DIR}/config.yaml" "${DEB_BUILD_DIR}/opt/${PROJECT_NAME}/"
 
-    # 创建空的日志和数据文件
-    touch "${DEB_BUILD_DIR}/opt/${PROJECT_NAME}/server.log"
-    touch "${DEB_BUILD_DIR}/opt/${PROJECT_NAME}/data.json"
-
     # 创建systemd服务文件
     cat > "${DEB_BUILD_DIR}/etc/systemd/system/${PROJECT_NAME}.service" << 'EOF'
 [Unit]
@@ -172,6 +173,7 @@ After=network.target
 Type=simple
 User=root
 WorkingDirectory=/opt/port-monitor
+StateDirectory=port-monitor
 ExecStart=/usr/bin/port-monitor
 Restart=always
 RestartSec=3
@@ -193,13 +195,23 @@ Description: 网络端口监控工具
  Port Monitor 是一个轻量级的网络端口监控工具，用于实时监控系统中 TCP/UDP 服务和网络接口状态。
 EOF
 
+    # 创建preinst脚本：旧版本的数据文件保存在/opt/port-monitor下，升级时迁移到状态目录
+    cat > "${DEB_BUILD_DIR}/DEBIAN/preinst" << 'EOF'
+#!/bin/bash
+if [ -s /opt/port-monitor/data.json ] && [ ! -e /var/lib/port-monitor/data.json ]; then
+    mkdir -p /var/lib/port-monitor
+    cp -p /opt/port-monitor/data.json /var/lib/port-monitor/data.json
+fi
+EOF
+
+    chmod 755 "${DEB_BUILD_DIR}/DEBIAN/preinst"
+
     # 创建postinst脚本
     cat > "${DEB_BUILD_DIR}/DEBIAN/postinst" << 'EOF'
 #!/bin/bash
 chmod 755 /usr/bin/port-monitor
 chmod 644 /opt/port-monitor/config.yaml
-chmod 666 /opt/port-monitor/server.log
-chmod 666 /opt/port-monitor/data.json
+chmod 755 /var/lib/port-monitor
 systemctl daemon-reload
 EOF
 
@@ -8,12 +8,54 @@ let currentConfigTable = '';
 let columnConfigs = {};
 // 存储URL路径映射
 let urlPaths = {};
+// 最近一次加载的服务和接口数据，切换筛选条件时无需重新请求
+let lastServices = [];
+let lastInterfaces = [];
+// 当前选中的网络命名空间（空字符串表示全部）
+let currentNetns = '';
+// 是否开启连接模式（统计每个监听端口的客户端连接）
+let connectionsMode = false;
+// 实时推送的服务，按key索引
+let streamServices = {};
+// 有正在编辑的单元格、暂缓重新渲染的表格
+let pendingTables = new Set();
+
+// 服务表格，依次为TCPv4、TCPv6、UDPv4、UDPv6、SCTP和原始套接字
+const serviceTables = [
+    'tcpv4-services-list',
+    'tcpv6-services-list',
+    'udpv4-services-list',
+    'udpv6-services-list',
+    'sctp-services-list',
+    'raw-services-list'
+];
 
 window.onload = function() {
     loadInterfaces();
     loadServices();
+    initChangesRange();
+    connectStream();
 };
 
+// 转义插入HTML（文本或属性值）的字符串
+function escapeHTML(text) {
+    return String(text === undefined || text === null ? '' : text)
+        .replace(/&/g, '&amp;')
+        .replace(/</g, '&lt;')
+        .replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;')
+        .replace(/'/g, '&#39;');
+}
+
+// 转义作为事件属性（onclick等）中单引号JS字符串参数的值：先按JS字符串转义，再按HTML属性转义
+function escapeJSArg(text) {
+    return escapeHTML(String(text === undefined || text === null ? '' : text)
+        .replace(/\\/g, '\\\\')
+        .replace(/'/g, "\\'")
+        .replace(/\n/g, '\\n')
+        .replace(/\r/g, '\\r'));
+}
+
 // 从服务器加载已保存的服务名称
 function loadServiceNamesFromServer() {
     return fetch('/api/saved-service-names')
@@ -56,31 +98,36 @@ function loadInterfaces() {
             }
             return fetch('/api/interfaces').then(response => response.json());
         })
-        .then(data => {
-            let html = '<table><tr><th>接口名称</th><th>IP地址</th><th>获取超链接</th></tr>';
-            // 检查数据是否存在且不为空
-            if (data && Array.isArray(data) && data.length > 0) {
-                data.forEach(iface => {
-                    // 检查每个接口对象的属性是否存在
-                    const name = iface.name || 'N/A';
-                    const ip = iface.ip || 'N/A';
-                    // 检查接口链接是否启用，默认为true
-                    const showLinks = interfaceConfigs[name] !== undefined ? interfaceConfigs[name] : true;
-                    html += '<tr><td>' + name + '</td><td>' + ip + '</td><td><label class="switch"><input type="checkbox" onchange="toggleInterfaceLink(\'' + name + '\', this.checked)" ' + (showLinks ? 'checked' : '') + '><span class="slider"></span></label></td></tr>';
-                });
-            } else {
-                html += '<tr><td colspan="3">未找到网络接口</td></tr>';
-            }
-            html += '</table>';
-            document.getElementById('interfaces-list').innerHTML = html;
-        })
+        .then(data => displayInterfaces(data))
         .catch(error => {
             console.error('Error loading interfaces:', error);
             document.getElementById('interfaces-list').innerHTML = '<p>加载接口信息失败</p>';
         });
 }
 
+function displayInterfaces(data) {
+    let html = '<table><tr><th>接口名称</th><th>IP地址</th><th>获取超链接</th></tr>';
+    // 检查数据是否存在且不为空
+    if (data && Array.isArray(data) && data.length > 0) {
+        data.forEach(iface => {
+            // 检查每个接口对象的属性是否存在
+            const name = iface.name || 'N/A';
+            const ip = iface.ip || 'N/A';
+            // 检查接口链接是否启用，默认为true
+            const showLinks = interfaceConfigs[name] !== undefined ? interfaceConfigs[name] : true;
+            html += '<tr><td>' + name + '</td><td>' + ip + '</td><td><label class="switch"><input type="checkbox" onchange="toggleInterfaceLink(\'' + name + '\', this.checked)" ' + (showLinks ? 'checked' : '') + '><span class="slider"></span></label></td></tr>';
+        });
+    } else {
+        html += '<tr><td colspan="3">未找到网络接口</td></tr>';
+    }
+    html += '</table>';
+    document.getElementById('interfaces-list').innerHTML = html;
+}
+
 function loadServices() {
+    loadUnixSockets();
+    loadBaselineMissing();
+    
     // 先加载保存的服务名称和接口配置，再加载服务列表
     fetch('/api/saved-service-names')
         .then(response => response.json())
@@ -121,37 +168,15 @@ function loadServices() {
             }
             
             return Promise.all([
-                fetch('/api/services').then(response => response.json()),
+                fetch('/api/services' + (connectionsMode ? '?connections=1' : '')).then(response => response.json()),
                 fetch('/api/interfaces').then(response => response.json())
             ]);
         })
         .then(([services, interfaces]) => {
-        // 分离TCPv4、TCPv6、UDPv4和UDPv6服务
-        const tcpv4Services = services.filter(service => service.protocol === 'tcp' && 
-            (service.local_addr === '0.0.0.0' || service.local_addr === '*' || 
-             (service.local_addr.indexOf(':') === -1 && service.local_addr !== '::')));
-        const tcpv6Services = services.filter(service => service.protocol === 'tcp' && 
-            (service.local_addr === '::' || service.local_addr.indexOf(':') !== -1));
-        const udpv4Services = services.filter(service => service.protocol === 'udp' && 
-            (service.local_addr === '0.0.0.0' || service.local_addr === '*' || 
-             (service.local_addr.indexOf(':') === -1 && service.local_addr !== '::')));
-        const udpv6Services = services.filter(service => service.protocol === 'udp' && 
-            (service.local_addr === '::' || service.local_addr.indexOf(':') !== -1));
-        
-        // 排序服务（按端口号）
-        tcpv4Services.sort((a, b) => parseInt(a.local_port) - parseInt(b.local_port));
-        tcpv6Services.sort((a, b) => parseInt(a.local_port) - parseInt(b.local_port));
-        udpv4Services.sort((a, b) => parseInt(a.local_port) - parseInt(b.local_port));
-        udpv6Services.sort((a, b) => parseInt(a.local_port) - parseInt(b.local_port));
-        
-        // 显示TCPv4服务
-        displayServices(tcpv4Services, interfaces, 'tcpv4-services-list');
-        // 显示TCPv6服务
-        displayServices(tcpv6Services, interfaces, 'tcpv6-services-list');
-        // 显示UDPv4服务
-        displayServices(udpv4Services, interfaces, 'udpv4-services-list');
-        // 显示UDPv6服务
-        displayServices(udpv6Services, interfaces, 'udpv6-services-list');
+        lastServices = services || [];
+        lastInterfaces = interfaces || [];
+        updateNetnsFilter(lastServices);
+        renderServices(lastServices, lastInterfaces);
     })
     .catch(error => {
         console.error('Error loading services:', error);
@@ -159,7 +184,450 @@ function loadServices() {
         document.getElementById('tcpv6-services-list').innerHTML = '<p>加载服务信息失败</p>';
         document.getElementById('udpv4-services-list').innerHTML = '<p>加载服务信息失败</p>';
         document.getElementById('udpv6-services-list').innerHTML = '<p>加载服务信息失败</p>';
+        document.getElementById('sctp-services-list').innerHTML = '<p>加载服务信息失败</p>';
+        document.getElementById('raw-services-list').innerHTML = '<p>加载服务信息失败</p>';
+    });
+}
+
+// 加载Unix域套接字
+function loadUnixSockets() {
+    fetch('/api/unix-sockets')
+        .then(response => response.json())
+        .then(sockets => {
+            displayUnixSockets(sockets);
+        })
+        .catch(error => {
+            console.error('Error loading unix sockets:', error);
+            document.getElementById('unix-sockets-list').innerHTML = '<p>加载Unix套接字失败</p>';
+        });
+}
+
+function displayUnixSockets(sockets) {
+    let html = '<table><tr><th>进程名称</th><th>路径</th><th>类型</th><th>状态</th></tr>';
+    
+    if (sockets && Array.isArray(sockets) && sockets.length > 0) {
+        sockets.sort((a, b) => a.path.localeCompare(b.path));
+        sockets.forEach(socket => {
+            // 路径和进程信息由本机用户控制，必须转义后再插入页面
+            const detail = escapeHTML(formatProcessDetail(socket));
+            const path = escapeHTML(socket.path || '(匿名)');
+            html += '<tr>';
+            html += '<td title="' + detail + '">' + escapeHTML(socket.name || 'N/A') + '</td>';
+            html += '<td title="' + path + '" data-path="' + escapeHTML(socket.path) + '" style="cursor: pointer;" onmouseover="hoverEffect(this)" onmouseout="normalEffect(this)">' + path + '</td>';
+            html += '<td>' + escapeHTML(socket.type) + '</td>';
+            html += '<td>' + escapeHTML(socket.state) + '</td>';
+            html += '</tr>';
+        });
+    } else {
+        html += '<tr><td colspan="4">未找到Unix套接字</td></tr>';
+    }
+    html += '</table>';
+    
+    const list = document.getElementById('unix-sockets-list');
+    list.innerHTML = html;
+    // 复制的路径从data属性读取，不拼接到onclick中
+    list.querySelectorAll('td[data-path]').forEach(cell => {
+        cell.addEventListener('click', event => copyToClipboard(event, cell.dataset.path));
+    });
+}
+
+// 变更视图默认比较最近24小时
+function initChangesRange() {
+    const from = new Date(Date.now() - 24 * 3600 * 1000);
+    // datetime-local 需要本地时间的 YYYY-MM-DDTHH:MM 格式
+    const local = new Date(from.getTime() - from.getTimezoneOffset() * 60000);
+    document.getElementById('changes-from').value = local.toISOString().slice(0, 16);
+}
+
+// 查询两个时间点之间的服务变化，未填写结束时间时与当前比较
+function loadChanges() {
+    const fromValue = document.getElementById('changes-from').value;
+    const toValue = document.getElementById('changes-to').value;
+    const result = document.getElementById('changes-result');
+    if (!fromValue) {
+        result.innerHTML = '<p>请选择开始时间</p>';
+        return;
+    }
+
+    let url = '/api/history/diff?from=' + Math.floor(new Date(fromValue).getTime() / 1000);
+    if (toValue) {
+        url += '&to=' + Math.floor(new Date(toValue).getTime() / 1000);
+    }
+
+    fetch(url)
+        .then(response => {
+            if (!response.ok) {
+                return response.text().then(text => { throw new Error(text.trim()); });
+            }
+            return response.json();
+        })
+        .then(diff => {
+            displayChanges(diff);
+        })
+        .catch(error => {
+            console.error('Error loading changes:', error);
+            result.innerHTML = '<p>加载变更失败: ' + error.message + '</p>';
+        });
+}
+
+const changeReasonNames = {
+    'address': '监听地址变化',
+    'process': '进程变化',
+    'pid': '进程重启',
+    'state': '状态变化'
+};
+
+function formatChangeAddress(service) {
+    const addr = service.local_addr.includes(':') ? '[' + service.local_addr + ']' : service.local_addr;
+    return addr + ':' + service.local_port;
+}
+
+function displayChanges(diff) {
+    const added = diff.added || [];
+    const removed = diff.removed || [];
+    const changed = diff.changed || [];
+
+    let html = '<p>快照 #' + diff.from.id + '（' + new Date(diff.from.time).toLocaleString() + '）至 ' +
+        (diff.to.id ? '#' + diff.to.id + '（' + new Date(diff.to.time).toLocaleString() + '）' : '当前') +
+        '：新增 ' + added.length + ' 个，移除 ' + removed.length + ' 个，变化 ' + changed.length + ' 个</p>';
+
+    if (added.length === 0 && removed.length === 0 && changed.length === 0) {
+        document.getElementById('changes-result').innerHTML = html;
+        return;
+    }
+
+    html += '<table><tr><th>变更</th><th>协议</th><th>监听地址</th><th>进程名称</th><th>说明</th></tr>';
+    added.forEach(service => {
+        html += '<tr class="diff-added"><td>新增</td><td>' + service.protocol + '</td><td>' + formatChangeAddress(service) +
+            '</td><td title="' + formatProcessDetail(service).replace(/"/g, '&quot;') + '">' + service.name + '</td><td></td></tr>';
+    });
+    removed.forEach(service => {
+        html += '<tr class="diff-removed"><td>移除</td><td>' + service.protocol + '</td><td>' + formatChangeAddress(service) +
+            '</td><td title="' + formatProcessDetail(service).replace(/"/g, '&quot;') + '">' + service.name + '</td><td></td></tr>';
+    });
+    changed.forEach(change => {
+        const previous = change.previous;
+        const current = change.current;
+        const notes = change.reasons.map(reason => {
+            switch (reason) {
+                case 'address':
+                    return changeReasonNames[reason] + ': ' + formatChangeAddress(previous) + ' → ' + formatChangeAddress(current);
+                case 'process':
+                    return changeReasonNames[reason] + ': ' + previous.name + ' → ' + current.name;
+                case 'pid':
+                    return changeReasonNames[reason] + ': ' + (previous.process ? previous.process.pid : 'N/A') + ' → ' + (current.process ? current.process.pid : 'N/A');
+                case 'state':
+                    return changeReasonNames[reason] + ': ' + previous.state + ' → ' + current.state;
+                default:
+                    return reason;
+            }
+        });
+        html += '<tr class="diff-changed"><td>变化</td><td>' + current.protocol + '</td><td>' + formatChangeAddress(current) +
+            '</td><td title="' + formatProcessDetail(current).replace(/"/g, '&quot;') + '">' + current.name + '</td><td>' + notes.join('<br>') + '</td></tr>';
     });
+    html += '</table>';
+    document.getElementById('changes-result').innerHTML = html;
+}
+
+function formatBaselineCell(baseline) {
+    if (baseline === 'expected') {
+        return '<td><span class="badge badge-ok" title="在基线中声明的监听">预期</span></td>';
+    }
+    if (baseline === 'unexpected') {
+        return '<td><span class="badge badge-danger" title="基线中没有声明的监听">基线外</span></td>';
+    }
+    return '<td>-</td>';
+}
+
+const exposureClassNames = {
+    'loopback': '本机',
+    'private': '内网',
+    'public': '公网'
+};
+
+const exposureSeverityBadges = {
+    'high': 'badge-danger',
+    'medium': 'badge-warning',
+    'low': 'badge-info',
+    'none': 'badge-ok'
+};
+
+// 暴露范围，敏感端口暴露时按风险等级高亮，悬停显示原因和可访问的地址
+function formatExposureCell(exposure) {
+    if (!exposure) {
+        return '<td>-</td>';
+    }
+    let title = exposure.reason || '';
+    if (exposure.sensitive) {
+        title += '\n敏感服务: ' + exposure.sensitive;
+    }
+    if (exposure.addresses && exposure.addresses.length > 0) {
+        title += '\n可访问地址: ' + exposure.addresses.join(', ');
+    }
+    let text = exposureClassNames[exposure.class] || exposure.class;
+    if (exposure.severity === 'high' || exposure.severity === 'medium') {
+        text += ' ⚠';
+    }
+    const badge = exposureSeverityBadges[exposure.severity] || 'badge-ok';
+    return '<td><span class="badge ' + badge + '" title="' + title.replace(/"/g, '&quot;') + '">' + text + '</span></td>';
+}
+
+// 最近一次健康探测结果，悬停显示探测地址、状态码和错误
+function formatHealthCell(health) {
+    if (!health) {
+        return '<td>-</td>';
+    }
+    let title = health.type.toUpperCase() + ' ' + health.target + '\n时间: ' + new Date(health.time).toLocaleString();
+    if (health.status_code) {
+        title += '\n状态码: ' + health.status_code;
+    }
+    if (health.error) {
+        title += '\n错误: ' + health.error;
+    }
+    const latency = health.latency_ms.toFixed(1) + 'ms';
+    if (health.status === 'up') {
+        return '<td><span class="badge badge-ok" title="' + title.replace(/"/g, '&quot;') + '">正常 ' + latency + '</span></td>';
+    }
+    title += '\n连续失败: ' + health.failures + ' 次';
+    return '<td><span class="badge badge-danger" title="' + title.replace(/"/g, '&quot;') + '">异常</span></td>';
+}
+
+// TLS证书剩余天数，即将过期或无效时高亮，悬停显示证书详情
+function formatTLSCell(tls) {
+    if (!tls) {
+        return '<td>-</td>';
+    }
+    let title = '主题: ' + tls.subject + '\n颁发者: ' + tls.issuer + '\n协议: ' + tls.version +
+        '\n有效期: ' + new Date(tls.not_before).toLocaleDateString() + ' ~ ' + new Date(tls.not_after).toLocaleDateString();
+    if (tls.sans && tls.sans.length > 0) {
+        title += '\nSAN: ' + tls.sans.join(', ');
+    }
+    title += '\n证书链: ' + (tls.chain_valid ? '有效' : '无效');
+    if (tls.warnings && tls.warnings.length > 0) {
+        title += '\n\n' + tls.warnings.join('\n');
+    }
+    title = escapeHTML(title);
+    if (tls.status === 'expired') {
+        return '<td><span class="badge badge-danger" title="' + title + '">已过期</span></td>';
+    }
+    if (tls.status === 'warning') {
+        return '<td><span class="badge badge-warning" title="' + title + '">' + tls.days_left + '天 ⚠</span></td>';
+    }
+    return '<td><span class="badge badge-ok" title="' + title + '">' + tls.days_left + '天</span></td>';
+}
+
+// HTTP端点的访问结果，悬停显示重定向链和响应时间
+function formatEndpoint(endpoints, ip) {
+    if (!endpoints) {
+        return '';
+    }
+    const endpoint = endpoints.find(item => item.ip === ip);
+    if (!endpoint) {
+        return '';
+    }
+    let title = endpoint.url + '\n响应时间: ' + endpoint.response_time_ms.toFixed(1) + 'ms';
+    if (endpoint.redirects && endpoint.redirects.length > 0) {
+        title += '\n重定向:';
+        endpoint.redirects.forEach(redirect => {
+            title += '\n  ' + redirect.status_code + ' ' + redirect.url;
+        });
+        title += '\n  -> ' + (endpoint.final_url || endpoint.url);
+    }
+    if (endpoint.location) {
+        title += '\n未跟随的重定向: ' + endpoint.location;
+    }
+    if (endpoint.server) {
+        title += '\nServer: ' + endpoint.server;
+    }
+    if (endpoint.error) {
+        title += '\n错误: ' + endpoint.error;
+        return ' <span class="badge badge-danger" title="' + escapeHTML(title) + '">失败</span>';
+    }
+
+    let badge = 'badge-ok';
+    if (endpoint.status_code >= 500) {
+        badge = 'badge-danger';
+    } else if (endpoint.status_code >= 400) {
+        badge = 'badge-warning';
+    }
+    let text = String(endpoint.status_code);
+    if (endpoint.redirects && endpoint.redirects.length > 0) {
+        text = endpoint.redirects[0].status_code + '→' + text;
+    }
+    const pageTitle = endpoint.title ? ' <span class="endpoint-title">' + escapeHTML(endpoint.title) + '</span>' : '';
+    return ' <span class="badge ' + badge + '" title="' + escapeHTML(title) + '">' + text + '</span>' + pageTitle;
+}
+
+// 显示基线中声明但当前没有监听的端口
+function loadBaselineMissing() {
+    fetch('/api/baseline')
+        .then(response => response.json())
+        .then(status => {
+            const element = document.getElementById('baseline-missing');
+            if (!status.configured || !status.missing || status.missing.length === 0) {
+                element.style.display = 'none';
+                return;
+            }
+            const items = status.missing.map(rule => {
+                let text = (rule.protocol || '*') + ' ' + (rule.address || '*') + ':' + rule.port;
+                if (rule.process) {
+                    text += ' (' + rule.process + ')';
+                }
+                if (rule.description) {
+                    text += ' - ' + rule.description;
+                }
+                return '<span class="badge badge-warning">' + escapeHTML(text) + '</span>';
+            });
+            element.innerHTML = '基线中缺失的监听: ' + items.join(' ');
+            element.style.display = 'block';
+        })
+        .catch(error => {
+            console.error('Error loading baseline:', error);
+        });
+}
+
+// 切换连接模式
+function toggleConnectionsMode(enabled) {
+    connectionsMode = enabled;
+    loadServices();
+}
+
+// 根据服务中出现的网络命名空间生成筛选下拉框
+function updateNetnsFilter(services) {
+    const select = document.getElementById('netns-filter');
+    const namespaces = [...new Set(services.map(service => service.netns).filter(ns => ns))].sort();
+    
+    // 只有主机命名空间时不显示筛选框
+    if (namespaces.length <= 1) {
+        select.style.display = 'none';
+        currentNetns = '';
+        return;
+    }
+    
+    if (currentNetns && namespaces.indexOf(currentNetns) === -1) {
+        currentNetns = '';
+    }
+    
+    let html = '<option value="">全部命名空间</option>';
+    namespaces.forEach(ns => {
+        html += '<option value="' + ns + '"' + (ns === currentNetns ? ' selected' : '') + '>' + ns + '</option>';
+    });
+    select.innerHTML = html;
+    select.style.display = 'inline-block';
+}
+
+// 切换网络命名空间筛选
+function filterNetns(netns) {
+    currentNetns = netns;
+    renderServices(lastServices, lastInterfaces);
+}
+
+// 服务所在的表格。SCTP和原始套接字数量较少，IPv4和IPv6合并显示
+function serviceTableId(service) {
+    if (service.protocol === 'sctp' || service.protocol === 'raw') {
+        return service.protocol + '-services-list';
+    }
+    const family = service.local_addr.indexOf(':') !== -1 ? 'v6' : 'v4';
+    return service.protocol + family + '-services-list';
+}
+
+// 按协议和地址族拆分服务并渲染各个表格，tables不为空时只渲染其中的表格
+function renderServices(services, interfaces, tables) {
+    if (currentNetns) {
+        services = services.filter(service => service.netns === currentNetns);
+    }
+    
+    const groups = {};
+    serviceTables.forEach(elementId => groups[elementId] = []);
+    services.forEach(service => {
+        const elementId = serviceTableId(service);
+        if (groups[elementId]) {
+            groups[elementId].push(service);
+        }
+    });
+    
+    serviceTables.forEach(elementId => {
+        if (tables && !tables.has(elementId)) {
+            return;
+        }
+        // 正在编辑服务名称或URL路径时不重新渲染，等下次更新
+        if (tables && document.querySelector('#' + elementId + ' .edit-input')) {
+            pendingTables.add(elementId);
+            return;
+        }
+        pendingTables.delete(elementId);
+        // 排序服务（按端口号）
+        groups[elementId].sort((a, b) => parseInt(a.local_port) - parseInt(b.local_port));
+        displayServices(groups[elementId], interfaces, elementId);
+    });
+}
+
+// 订阅实时推送：连接时收到完整数据，之后只收到变化的服务和网卡，
+// 只重新渲染受影响的表格。断开后浏览器会自动重新连接
+function connectStream() {
+    if (!window.EventSource) {
+        return;
+    }
+    const source = new EventSource('/api/stream');
+    
+    source.onopen = () => setStreamStatus(true);
+    source.onerror = () => {
+        setStreamStatus(false);
+        // 后台禁用了实时推送时不再重试
+        if (source.readyState === EventSource.CLOSED) {
+            console.error('实时推送不可用，请手动刷新');
+        }
+    };
+    
+    source.addEventListener('snapshot', event => {
+        const snapshot = JSON.parse(event.data);
+        streamServices = {};
+        snapshot.services.forEach(service => streamServices[service.key] = service);
+        lastInterfaces = snapshot.interfaces || [];
+        displayInterfaces(lastInterfaces);
+        if (!connectionsMode) {
+            lastServices = Object.values(streamServices);
+            updateNetnsFilter(lastServices);
+        }
+        renderServices(lastServices, lastInterfaces);
+    });
+    
+    source.addEventListener('services', event => {
+        const update = JSON.parse(event.data);
+        const tables = new Set(pendingTables);
+        update.removed.forEach(key => {
+            if (streamServices[key]) {
+                tables.add(serviceTableId(streamServices[key]));
+                delete streamServices[key];
+            }
+        });
+        update.added.concat(update.updated).forEach(service => {
+            streamServices[service.key] = service;
+            tables.add(serviceTableId(service));
+        });
+        
+        // 连接模式下的数据包含连接统计，由手动刷新更新
+        if (connectionsMode) {
+            return;
+        }
+        lastServices = Object.values(streamServices);
+        updateNetnsFilter(lastServices);
+        renderServices(lastServices, lastInterfaces, tables);
+    });
+    
+    source.addEventListener('interfaces', event => {
+        lastInterfaces = JSON.parse(event.data).interfaces || [];
+        displayInterfaces(lastInterfaces);
+        // 访问链接使用网卡地址，需要重新渲染
+        renderServices(lastServices, lastInterfaces);
+    });
+}
+
+// 显示实时推送的连接状态
+function setStreamStatus(connected) {
+    const status = document.getElementById('stream-status');
+    status.textContent = connected ? '实时' : '实时推送已断开';
+    status.className = 'stream-status' + (connected ? ' connected' : '');
 }
 
 function displayServices(services, interfaces, elementId) {
@@ -173,6 +641,15 @@ function displayServices(services, interfaces, elementId) {
         'protocol': true,
         'listen_addr': true,
         'state': true,
+        'systemd_unit': false,
+        'container': false,
+        'netns': false,
+        'connections': true,
+        'queue': false,
+        'baseline': true,
+        'exposure': true,
+        'health': true,
+        'tls': true,
         'url_path': true,
         'access_links': true
     };
@@ -212,6 +689,48 @@ function displayServices(services, interfaces, elementId) {
         html += '<th onclick="sortTable(this, 1, \'' + elementId + '\')" style="cursor: pointer;">状态 <span style="font-size: 12px;">↕</span></th>';
     }
     
+    if (columnConfigs[tableType]['systemd_unit']) {
+        html += '<th>Systemd单元</th>';
+    }
+    
+    if (columnConfigs[tableType]['container']) {
+        html += '<th>容器</th>';
+    }
+    
+    if (columnConfigs[tableType]['netns']) {
+        html += '<th>网络命名空间</th>';
+    }
+    
+    // 客户端连接列只在连接模式下显示
+    const showConnections = connectionsMode && columnConfigs[tableType]['connections'];
+    if (showConnections) {
+        html += '<th>客户端连接</th>';
+    }
+    
+    if (columnConfigs[tableType]['queue']) {
+        html += '<th>队列(Recv/Send)</th>';
+    }
+    
+    // 基线列只在配置了基线文件时显示
+    const showBaseline = columnConfigs[tableType]['baseline'] && services.some(service => service.baseline);
+    if (showBaseline) {
+        html += '<th>基线</th>';
+    }
+    
+    if (columnConfigs[tableType]['exposure']) {
+        html += '<th>暴露</th>';
+    }
+    
+    if (columnConfigs[tableType]['health']) {
+        html += '<th>健康</th>';
+    }
+    
+    // 证书列只在有支持TLS的监听时显示
+    const showTLS = columnConfigs[tableType]['tls'] && services.some(service => service.tls);
+    if (showTLS) {
+        html += '<th>证书</th>';
+    }
+    
     // 添加URL路径列标题
     if (columnConfigs[tableType]['url_path']) {
         html += '<th>URL路径</th>';
@@ -235,10 +754,13 @@ function displayServices(services, interfaces, elementId) {
             const localAddr = service.local_addr || 'N/A';
             const localPort = service.local_port || 'N/A';
             const state = service.state || 'N/A';
-            const pid = service.pid || '';
+            const pid = formatProcessDetail(service);
             
-            // 生成唯一标识符用于编辑
-            const serviceId = localAddr + ':' + localPort + ':' + protocol;
+            // 生成唯一标识符用于编辑，与后端一致：其他网络命名空间中的服务加上命名空间前缀
+            let serviceId = localAddr + ':' + localPort + ':' + protocol;
+            if (service.netns && service.netns !== 'host') {
+                serviceId = service.netns + '|' + serviceId;
+            }
             
             html += '<tr>';
             
@@ -246,16 +768,16 @@ function displayServices(services, interfaces, elementId) {
             if (columnConfigs[tableType]['process_name']) {
                 // 转义引号以确保在HTML属性中正确显示
                 const escapedPid = pid.replace(/"/g, '&quot;');
-                html += '<td title="' + escapedPid + '" style="position: relative; cursor: pointer;" onmouseover="hoverEffect(this)" onmouseout="normalEffect(this)" onclick="copyToClipboard(this, \'' + escapedPid.replace(/'/g, "\\'") + '\')">' + name + '</td>';
+                html += '<td title="' + escapedPid + '" style="position: relative; cursor: pointer;" onmouseover="hoverEffect(this)" onmouseout="normalEffect(this)" onclick="copyToClipboard(this, \'' + escapedPid.replace(/\\/g, '\\\\').replace(/'/g, "\\'").replace(/\n/g, '\\n') + '\')">' + name + '</td>';
             }
             
             // 服务名称列
             if (columnConfigs[tableType]['service_name']) {
-                // 获取服务名称，先从用户定义获取，再从端口映射获取
-                let serviceName = serviceNames[serviceId] || getServiceNameByPort(localPort);
+                // 获取服务名称，先从用户定义获取，再从协议识别结果获取
+                let serviceName = serviceNames[serviceId] || getServiceNameByFingerprint(service.fingerprint);
                 
-                html += '<td id="service-name-' + serviceId + '">' + serviceName;
-                html += ' <span class="edit-icon" onclick="editServiceName(\'' + serviceId + '\', \'' + serviceName + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span></td>';
+                html += '<td id="service-name-' + escapeHTML(serviceId) + '" title="' + escapeHTML(formatFingerprintTitle(service.fingerprint)) + '">' + escapeHTML(serviceName);
+                html += ' <span class="edit-icon" onclick="editServiceName(\'' + escapeJSArg(serviceId) + '\', \'' + escapeJSArg(serviceName) + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span></td>';
             }
             
             // 协议列
@@ -282,13 +804,82 @@ function displayServices(services, interfaces, elementId) {
                 html += '<td>' + state + '</td>';
             }
             
+            // Systemd单元列，悬停显示slice和完整cgroup路径
+            if (columnConfigs[tableType]['systemd_unit']) {
+                const unit = service.unit || service.cgroup || '-';
+                let cgroupTitle = '';
+                if (service.slice) {
+                    cgroupTitle += 'Slice: ' + service.slice + '\n';
+                }
+                if (service.cgroup) {
+                    cgroupTitle += 'Cgroup: ' + service.cgroup;
+                }
+                html += '<td title="' + cgroupTitle.replace(/"/g, '&quot;') + '">' + unit + '</td>';
+            }
+            
+            // 容器列，悬停显示镜像和容器ID
+            if (columnConfigs[tableType]['container']) {
+                const containerName = service.container_name || (service.container_id ? service.container_id.substring(0, 12) : '-');
+                let containerTitle = '';
+                if (service.container_image) {
+                    containerTitle += '镜像: ' + service.container_image + '\n';
+                }
+                if (service.container_id) {
+                    containerTitle += 'ID: ' + service.container_id + ' (' + service.container_runtime + ')';
+                }
+                html += '<td title="' + containerTitle.replace(/"/g, '&quot;') + '">' + containerName + '</td>';
+            }
+            
+            // 网络命名空间列
+            if (columnConfigs[tableType]['netns']) {
+                const netnsTitle = service.netns_inode ? 'net:[' + service.netns_inode + ']' : '';
+                html += '<td title="' + netnsTitle + '">' + (service.netns || '-') + '</td>';
+            }
+            
+            // 客户端连接列，悬停显示状态分布和主要来源IP
+            if (showConnections) {
+                html += formatConnectionsCell(service.connections);
+            }
+            
+            // 队列列：监听套接字为 accept队列/backlog，饱和时高亮
+            if (columnConfigs[tableType]['queue']) {
+                const queueText = (service.recv_q || 0) + ' / ' + (service.backlog_unknown ? '?' : (service.send_q || 0));
+                if (service.backlog_unknown) {
+                    html += '<td title="当前采集方式（/proc/net）无法获取backlog">' + queueText + '</td>';
+                } else if (service.saturated) {
+                    html += '<td class="saturated" title="accept队列接近backlog上限">' + queueText + ' ⚠</td>';
+                } else {
+                    html += '<td>' + queueText + '</td>';
+                }
+            }
+            
+            // 基线列
+            if (showBaseline) {
+                html += formatBaselineCell(service.baseline);
+            }
+            
+            // 暴露列
+            if (columnConfigs[tableType]['exposure']) {
+                html += formatExposureCell(service.exposure);
+            }
+            
+            // 健康列
+            if (columnConfigs[tableType]['health']) {
+                html += formatHealthCell(service.health);
+            }
+            
+            // 证书列
+            if (showTLS) {
+                html += formatTLSCell(service.tls);
+            }
+            
             // URL路径列
             if (columnConfigs[tableType]['url_path']) {
                 // 获取URL路径
                 let urlPath = urlPaths[serviceId] || '/';
                 
-                html += '<td id="url-path-' + serviceId + '">' + urlPath;
-                html += ' <span class="edit-icon" onclick="editURLPath(\'' + serviceId + '\', \'' + urlPath + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span></td>';
+                html += '<td id="url-path-' + escapeHTML(serviceId) + '">' + urlPath;
+                html += ' <span class="edit-icon" onclick="editURLPath(\'' + escapeJSArg(serviceId) + '\', \'' + urlPath + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span></td>';
             }
             
             // 访问链接列
@@ -311,8 +902,11 @@ function displayServices(services, interfaces, elementId) {
                             const urlPath = urlPaths[serviceId] || '/';
                             const fullAddress = targetAddr + ':' + localPort + urlPath;
                             // 修改为显示网卡名称而不是IP地址
-                            html += '<a href="http://' + targetAddr + ':' + localPort + urlPath + '" target="_blank">' + iface.name + '</a> ' +
+                            const scheme = service.fingerprint && service.fingerprint.tls ? 'https' : 'http';
+                            html += '<a href="' + scheme + '://' + targetAddr + ':' + localPort + urlPath + '" target="_blank">' + iface.name + '</a> ' +
                                    '<button onclick="copyToClipboard(event, \'' + fullAddress.replace(/'/g, "\\'") + '\')" onmouseover="hoverEffect(this)" onmouseout="normalEffect(this)" style="margin-left: 5px; padding: 2px 5px; font-size: 12px;">复制</button>';
+                            // 后端访问该地址的结果：状态码、页面标题和响应时间
+                            html += formatEndpoint(service.endpoints, targetAddr);
                             linkCount++;
                         }
                     });
@@ -345,26 +939,89 @@ function displayServices(services, interfaces, elementId) {
     document.getElementById(elementId).innerHTML = html;
 }
 
-function getServiceNameByPort(port) {
-    const portMap = {
-        "22": "sshd",
-        "80": "http",
-        "443": "http",
-        "3306": "mysql",
-        "5432": "postgresql",
-        "6379": "redis",
-        "8080": "http"
-    };
+// 生成客户端连接统计单元格
+function formatConnectionsCell(stats) {
+    if (!stats) {
+        return '<td>-</td>';
+    }
     
-    return portMap[port] || "N/A";
+    let lines = [];
+    for (let state in stats.states) {
+        lines.push(state + ': ' + stats.states[state]);
+    }
+    if (stats.top_remotes && stats.top_remotes.length > 0) {
+        lines.push('来源IP:');
+        stats.top_remotes.forEach(remote => {
+            lines.push('  ' + remote.ip + ' (' + remote.count + ')');
+        });
+    }
+    return '<td title="' + lines.join('\n') + '">' + stats.clients + '</td>';
+}
+
+// 生成进程详细信息，用于悬停显示和复制
+function formatProcessDetail(service) {
+    const proc = service.process;
+    if (!proc) {
+        return service.pid || '';
+    }
+    
+    let lines = [];
+    lines.push('PID: ' + proc.pid + '  PPID: ' + proc.ppid);
+    if (proc.pids && proc.pids.length > 1) {
+        lines.push('共享进程: ' + proc.pids.join(', '));
+    }
+    if (proc.user) {
+        lines.push('用户: ' + proc.user + ' (' + proc.uid + ')');
+    }
+    if (proc.exe) {
+        lines.push('程序: ' + proc.exe);
+    }
+    if (proc.cmdline) {
+        lines.push('命令行: ' + proc.cmdline);
+    }
+    if (proc.start_time && !proc.start_time.startsWith('0001-')) {
+        lines.push('启动时间: ' + new Date(proc.start_time).toLocaleString());
+    }
+    return lines.join('\n');
+}
+
+// 后端识别到的协议和版本，没有识别结果时使用端口注册表中的服务名称。
+// 返回的是未转义的文本，产品和版本来自服务端的欢迎信息，插入HTML时需要用 escapeHTML 转义
+function getServiceNameByFingerprint(fingerprint) {
+    if (!fingerprint) {
+        return "N/A";
+    }
+    if (fingerprint.product) {
+        return fingerprint.product + (fingerprint.version ? ' ' + fingerprint.version : '');
+    }
+    if (fingerprint.protocol) {
+        return fingerprint.protocol + (fingerprint.tls && fingerprint.protocol !== 'tls' ? ' (TLS)' : '');
+    }
+    return fingerprint.service || "N/A";
+}
+
+function formatFingerprintTitle(fingerprint) {
+    if (!fingerprint) {
+        return '';
+    }
+    let title = '';
+    if (fingerprint.service) {
+        title += '端口注册: ' + fingerprint.service + '\n';
+    }
+    if (fingerprint.protocol) {
+        title += '识别协议: ' + fingerprint.protocol + (fingerprint.tls ? ' over TLS' : '') + '\n';
+    }
+    if (fingerprint.banner) {
+        title += 'Banner: ' + fingerprint.banner;
+    }
+    return title;
 }
 
 function editServiceName(serviceId, currentName) {
     const cell = document.getElementById('service-name-' + serviceId);
-    const escapedCurrentName = currentName.replace(/"/g, '&quot;').replace(/'/g, '&#39;');
-    cell.innerHTML = '<input type="text" class="edit-input" value="' + escapedCurrentName + '" id="edit-input-' + serviceId + '" onkeydown="handleEditKeyDown(event, \'' + serviceId + '\')"> ' +
-                    '<span class="save-icon" onclick="saveServiceName(\'' + serviceId + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span> ' +
-                    '<span class="cancel-icon" onclick="cancelEditServiceName(\'' + serviceId + '\', \'' + escapedCurrentName + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
+    cell.innerHTML = '<input type="text" class="edit-input" value="' + escapeHTML(currentName) + '" id="edit-input-' + escapeHTML(serviceId) + '" onkeydown="handleEditKeyDown(event, \'' + escapeJSArg(serviceId) + '\')"> ' +
+                    '<span class="save-icon" onclick="saveServiceName(\'' + escapeJSArg(serviceId) + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span> ' +
+                    '<span class="cancel-icon" onclick="cancelEditServiceName(\'' + escapeJSArg(serviceId) + '\', \'' + escapeJSArg(currentName) + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
     document.getElementById('edit-input-' + serviceId).focus();
 }
 
@@ -399,12 +1056,12 @@ function saveServiceName(serviceId) {
     });
     
     const cell = document.getElementById('service-name-' + serviceId);
-    cell.innerHTML = newName + ' <span class="edit-icon" onclick="editServiceName(\'' + serviceId + '\', \'' + newName + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
+    cell.innerHTML = escapeHTML(newName) + ' <span class="edit-icon" onclick="editServiceName(\'' + escapeJSArg(serviceId) + '\', \'' + escapeJSArg(newName) + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
 }
 
 function cancelEditServiceName(serviceId, originalName) {
     const cell = document.getElementById('service-name-' + serviceId);
-    cell.innerHTML = originalName + ' <span class="edit-icon" onclick="editServiceName(\'' + serviceId + '\', \'' + originalName + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
+    cell.innerHTML = escapeHTML(originalName) + ' <span class="edit-icon" onclick="editServiceName(\'' + escapeJSArg(serviceId) + '\', \'' + escapeJSArg(originalName) + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
 }
 
 function sortTable(header, columnIndex, elementId) {
@@ -588,6 +1245,15 @@ function showColumnConfig(event) {
         'protocol': true,
         'listen_addr': true,
         'state': true,
+        'systemd_unit': false,
+        'container': false,
+        'netns': false,
+        'connections': true,
+        'queue': false,
+        'baseline': true,
+        'exposure': true,
+        'health': true,
+        'tls': true,
         'url_path': true,
         'access_links': true
     };
@@ -608,6 +1274,15 @@ function showColumnConfig(event) {
         'protocol': '协议',
         'listen_addr': '监听地址',
         'state': '状态',
+        'systemd_unit': 'Systemd单元',
+        'container': '容器',
+        'netns': '网络命名空间',
+        'connections': '客户端连接',
+        'queue': '队列(Recv/Send)',
+        'baseline': '基线',
+        'exposure': '暴露',
+        'health': '健康',
+        'tls': '证书',
         'url_path': 'URL路径',
         'access_links': '访问链接'
     };
@@ -629,7 +1304,7 @@ function closeColumnConfig() {
 // 保存列配置
 function saveColumnConfig() {
     // 获取配置
-    const columnNames = ['process_name', 'service_name', 'protocol', 'listen_addr', 'state', 'url_path', 'access_links'];
+    const columnNames = ['process_name', 'service_name', 'protocol', 'listen_addr', 'state', 'systemd_unit', 'container', 'netns', 'connections', 'queue', 'baseline', 'exposure', 'health', 'tls', 'url_path', 'access_links'];
     const config = {};
     columnNames.forEach(col => {
         const checkbox = document.getElementById('col-' + col);
@@ -639,7 +1314,7 @@ function saveColumnConfig() {
     });
     
     // 更新所有表格的内存配置
-    const tableTypes = ['tcpv4', 'tcpv6', 'udpv4', 'udpv6'];
+    const tableTypes = ['tcpv4', 'tcpv6', 'udpv4', 'udpv6', 'sctp', 'raw'];
     tableTypes.forEach(tableType => {
         if (!columnConfigs[tableType]) {
             columnConfigs[tableType] = {};
@@ -695,9 +1370,9 @@ function normalEffect(element) {
 function editURLPath(serviceId, currentPath) {
     const cell = document.getElementById('url-path-' + serviceId);
     const escapedCurrentPath = currentPath.replace(/"/g, '&quot;').replace(/'/g, '&#39;');
-    cell.innerHTML = '<input type="text" class="edit-input" value="' + escapedCurrentPath + '" id="edit-url-input-' + serviceId + '" onkeydown="handleURLPathKeyDown(event, \'' + serviceId + '\')"> ' +
-                    '<span class="save-icon" onclick="saveURLPath(\'' + serviceId + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span> ' +
-                    '<span class="cancel-icon" onclick="cancelEditURLPath(\'' + serviceId + '\', \'' + escapedCurrentPath + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
+    cell.innerHTML = '<input type="text" class="edit-input" value="' + escapedCurrentPath + '" id="edit-url-input-' + escapeHTML(serviceId) + '" onkeydown="handleURLPathKeyDown(event, \'' + escapeJSArg(serviceId) + '\')"> ' +
+                    '<span class="save-icon" onclick="saveURLPath(\'' + escapeJSArg(serviceId) + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span> ' +
+                    '<span class="cancel-icon" onclick="cancelEditURLPath(\'' + escapeJSArg(serviceId) + '\', \'' + escapedCurrentPath + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
     document.getElementById('edit-url-input-' + serviceId).focus();
 }
 
@@ -736,20 +1411,22 @@ function saveURLPath(serviceId) {
         if (!response.ok) {
             throw new Error('保存失败');
         }
-        // 保存成功后刷新服务列表
-        loadServices();
+        // 保存成功后重新访问该服务并刷新服务列表
+        return fetch('/api/endpoints?service_id=' + encodeURIComponent(serviceId), { method: 'POST' })
+            .catch(() => {})
+            .then(() => loadServices());
     }).catch(error => {
         console.error('保存URL路径失败:', error);
     });
     
     const cell = document.getElementById('url-path-' + serviceId);
-    cell.innerHTML = newPath + ' <span class="edit-icon" onclick="editURLPath(\'' + serviceId + '\', \'' + newPath + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
+    cell.innerHTML = newPath + ' <span class="edit-icon" onclick="editURLPath(\'' + escapeJSArg(serviceId) + '\', \'' + newPath + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
 }
 
 // 添加取消编辑URL路径的函数
 function cancelEditURLPath(serviceId, originalPath) {
     const cell = document.getElementById('url-path-' + serviceId);
-    cell.innerHTML = originalPath + ' <span class="edit-icon" onclick="editURLPath(\'' + serviceId + '\', \'' + originalPath + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
+    cell.innerHTML = originalPath + ' <span class="edit-icon" onclick="editURLPath(\'' + escapeJSArg(serviceId) + '\', \'' + originalPath + '\')"><svg xmlns="http://www.w3.org/2000/svg" width="16" height="16" fill="currentColor" class="bi bi-pencil" viewBox="0 0 16 16"><path d="M12.146.146a.5.5 0 0 1 .708 0l3 3a.5.5 0 0 1 0 .708l-10 10a.5.5 0 0 1-.168.11l-5 2a.5.5 0 0 1-.65-.65l2-5a.5.5 0 0 1 .11-.168l10-10zM11.207 2.5 13.5 4.793 14.793 3.5 12.5 1.207 11.207 2.5zm1.586 3L10.5 3.207 4 9.707V10h.5a.5.5 0 0 1 .5.5v.5h.5a.5.5 0 0 1 .5.5v.5h.293l6.5-6.5zm-9.761 5.175-.106.106-1.528 3.821 3.821-1.528.106-.106A.5.5 0 0 1 5 12.5V12h-.5a.5.5 0 0 1-.5-.5V11h-.5a.5.5 0 0 1-.468-.325z"/></svg></span>';
 }
 
 // 添加生成随机端口的函数